@@ -0,0 +1,164 @@
+package cmd
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/hungpdn/mcp2grule/internal/config"
+	"github.com/hungpdn/mcp2grule/internal/pkg/exitcode"
+	"github.com/hungpdn/mcp2grule/internal/pkg/logger"
+	"github.com/hungpdn/mcp2grule/internal/storage"
+	"github.com/spf13/cobra"
+)
+
+var (
+	migrateCmd = &cobra.Command{
+		Use:   "migrate",
+		Short: "Manage database schema",
+		Long:  "Apply, revert or inspect the database schema migrations of DATABASE_TYPE",
+	}
+
+	migrateUpCmd = &cobra.Command{
+		Use:   "up",
+		Short: "Apply all pending migrations",
+		Args:  cobra.NoArgs,
+		Run:   runMigrateUp,
+	}
+
+	migrateDownCmd = &cobra.Command{
+		Use:   "down",
+		Short: "Revert the latest applied migrations",
+		Args:  cobra.NoArgs,
+		Run:   runMigrateDown,
+	}
+
+	migrateStatusCmd = &cobra.Command{
+		Use:   "status",
+		Short: "Show applied and pending migrations",
+		Args:  cobra.NoArgs,
+		Run:   runMigrateStatus,
+	}
+
+	migrateDownSteps int
+)
+
+func init() {
+	migrateDownCmd.Flags().IntVar(&migrateDownSteps, "steps", 1, "number of migrations to revert")
+
+	migrateCmd.AddCommand(migrateUpCmd, migrateDownCmd, migrateStatusCmd)
+}
+
+// runMigrateUp applies all pending migrations.
+func runMigrateUp(_ *cobra.Command, _ []string) {
+	ctx := context.Background()
+
+	migrator, closeDB := newMigrator(ctx)
+	defer closeDB()
+
+	n, err := migrator.Up(ctx)
+	if err != nil {
+		logger.Errorf("Failed to apply migrations: %v", err)
+		os.Exit(exitcode.DatabaseError)
+	}
+
+	logger.Infof("Applied %d migration(s)", n)
+}
+
+// runMigrateDown reverts the latest applied migrations.
+func runMigrateDown(_ *cobra.Command, _ []string) {
+	ctx := context.Background()
+
+	migrator, closeDB := newMigrator(ctx)
+	defer closeDB()
+
+	n, err := migrator.Down(ctx, migrateDownSteps)
+	if err != nil {
+		logger.Errorf("Failed to revert migrations: %v", err)
+		os.Exit(exitcode.DatabaseError)
+	}
+
+	logger.Infof("Reverted %d migration(s)", n)
+}
+
+// runMigrateStatus prints the state of every migration.
+func runMigrateStatus(_ *cobra.Command, _ []string) {
+	ctx := context.Background()
+
+	migrator, closeDB := newMigrator(ctx)
+	defer closeDB()
+
+	status, err := migrator.Status(ctx)
+	if err != nil {
+		logger.Errorf("Failed to read migration status: %v", err)
+		os.Exit(exitcode.DatabaseError)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+	for _, s := range status {
+		state, appliedAt := "pending", "-"
+		if s.Applied {
+			state, appliedAt = "applied", time.Unix(s.AppliedAt, 0).UTC().Format(time.RFC3339)
+		}
+		_, _ = fmt.Fprintf(w, "%04d\t%s\t%s\t%s\n", s.Version, s.Name, state, appliedAt)
+	}
+	_ = w.Flush()
+}
+
+// newMigrator opens the configured database and creates a migrator for it.
+func newMigrator(ctx context.Context) (*storage.Migrator, func()) {
+	db, dialect, err := openDatabase(ctx)
+	if err != nil {
+		logger.Errorf("Failed to open %s database: %v", config.App.DatabaseType, err)
+		os.Exit(exitcode.DatabaseError)
+	}
+
+	migrator, err := storage.NewMigrator(db, dialect)
+	if err != nil {
+		logger.Errorf("Failed to load migrations: %v", err)
+		_ = db.Close()
+		os.Exit(exitcode.DatabaseError)
+	}
+
+	return migrator, func() { _ = db.Close() }
+}
+
+// openDatabase opens the SQL database selected by DATABASE_TYPE.
+func openDatabase(ctx context.Context) (*sql.DB, storage.Dialect, error) {
+	switch config.App.DatabaseType {
+	case config.DatabaseTypeSQLite:
+		db, err := storage.OpenSQLite(config.App.SQLite)
+		return db, storage.DialectSQLite, err
+	case config.DatabaseTypePostgres:
+		db, err := storage.OpenPostgres(ctx, config.App.Postgres)
+		return db, storage.DialectPostgres, err
+	default:
+		return nil, "", fmt.Errorf("database type %s has no schema to migrate", config.App.DatabaseType)
+	}
+}
+
+// autoMigrate applies pending migrations on start when DATABASE_AUTO_MIGRATE is set.
+func autoMigrate(ctx context.Context, db *sql.DB, dialect storage.Dialect) error {
+	if !config.App.DatabaseAutoMigrate {
+		return nil
+	}
+
+	migrator, err := storage.NewMigrator(db, dialect)
+	if err != nil {
+		return err
+	}
+
+	n, err := migrator.Up(ctx)
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		logger.Infof("Applied %d migration(s)", n)
+	}
+
+	return nil
+}
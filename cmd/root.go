@@ -16,7 +16,7 @@ var (
 )
 
 func init() {
-	rootCmd.AddCommand(serverCmd)
+	rootCmd.AddCommand(serverCmd, migrateCmd)
 }
 
 func Execute() {
@@ -2,14 +2,20 @@ package cmd
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"os"
+	"time"
 
 	"github.com/hungpdn/mcp2grule/internal/api"
 	"github.com/hungpdn/mcp2grule/internal/api/handler"
 	"github.com/hungpdn/mcp2grule/internal/config"
 	"github.com/hungpdn/mcp2grule/internal/grule"
+	"github.com/hungpdn/mcp2grule/internal/pkg/auth"
+	"github.com/hungpdn/mcp2grule/internal/pkg/authz"
 	"github.com/hungpdn/mcp2grule/internal/pkg/exitcode"
 	"github.com/hungpdn/mcp2grule/internal/pkg/logger"
+	"github.com/hungpdn/mcp2grule/internal/pkg/middleware"
 	"github.com/hungpdn/mcp2grule/internal/storage"
 	"github.com/spf13/cobra"
 )
@@ -27,23 +33,170 @@ var (
 func runServer(_ *cobra.Command, _ []string) {
 	ctx := context.Background()
 
-	var store storage.IRulesetStorage
+	var (
+		store     storage.IRulesetStorage
+		pipelines storage.IPipelineStorage
+	)
 	switch config.App.DatabaseType {
 	case config.DatabaseTypeMemory:
-		store = storage.NewMemory()
+		memory := storage.NewMemory()
+		store, pipelines = memory, memory
+	case config.DatabaseTypeSQLite, config.DatabaseTypePostgres:
+		db, dialect, err := openDatabase(ctx)
+		if err != nil {
+			logger.Errorf("Failed to open %s database: %v", config.App.DatabaseType, err)
+			os.Exit(exitcode.DatabaseError)
+		}
+		defer db.Close()
+
+		if err := autoMigrate(ctx, db, dialect); err != nil {
+			logger.Errorf("Failed to migrate %s database: %v", config.App.DatabaseType, err)
+			os.Exit(exitcode.DatabaseError)
+		}
+
+		if dialect == storage.DialectSQLite {
+			sqlite := storage.NewSQLite(db)
+			store, pipelines = sqlite, sqlite
+		} else {
+			postgres := storage.NewPostgres(db)
+			store, pipelines = postgres, postgres
+		}
 	default:
 		logger.Errorf("Unsupported database driver: %s", config.App.DatabaseType)
 		os.Exit(exitcode.DatabaseError)
 	}
 
-	grule := grule.New(config.App.Grule, store)
+	store, pipelines = storage.Instrument(store, pipelines)
+
+	service := grule.New(config.App.Grule, store, pipelines)
+	if err := service.Warmup(ctx); err != nil {
+		logger.Errorf("Failed to load rulesets: %v", err)
+		os.Exit(exitcode.DatabaseError)
+	}
+
+	if config.App.RBAC.Enabled {
+		policy, anonymous, err := newPolicy(config.App.RBAC, config.App.MCPTransport)
+		if err != nil {
+			logger.Errorf("Failed to configure access control: %v", err)
+			os.Exit(exitcode.ConfigError)
+		}
+		service = grule.WithAuthorization(service, policy, anonymous)
+	}
+
+	mcpHandler := handler.NewMCPHandler(service)
+
+	// HTTP transports require a bearer token, stdio is only reachable by the parent process
+	var authenticator auth.Authenticator
+	if config.App.MCPTransport != config.MCPTransportStdio {
+		a, err := newAuthenticator(ctx, config.App.HTTPTransport, config.App.JWT)
+		if err != nil {
+			logger.Errorf("Failed to configure authentication: %v", err)
+			os.Exit(exitcode.AuthenticationError)
+		}
+		authenticator = a
+	}
 
-	mcpHandler := handler.NewMCPHandler(grule)
+	var limiter *middleware.RateLimiter
+	if cfg := config.App.RateLimit; cfg.Enabled {
+		limiter = middleware.NewRateLimiter(map[middleware.Budget]middleware.Rate{
+			middleware.BudgetRead:     {PerSecond: cfg.ReadRate, Burst: cfg.ReadBurst},
+			middleware.BudgetWrite:    {PerSecond: cfg.WriteRate, Burst: cfg.WriteBurst},
+			middleware.BudgetEvaluate: {PerSecond: cfg.EvaluateRate, Burst: cfg.EvaluateBurst},
+		})
+	}
 
-	mcpServer := api.NewServer(AppName, Version, mcpHandler)
+	mcpServer := api.NewServer(AppName, Version, mcpHandler, authenticator, limiter)
 
 	if err := mcpServer.Run(ctx); err != nil {
 		logger.Errorf("Failed to start MCP server: %v", err)
 		os.Exit(exitcode.MCPTransportError)
 	}
 }
+
+// newPolicy loads the access control policy and returns it with the role of the calls without principal:
+// RBAC_STDIO_ROLE over stdio, none over HTTP where every call is authenticated
+func newPolicy(cfg config.RBAC, transport config.MCPTransport) (*authz.Policy, authz.Role, error) {
+
+	defaultRole := authz.RoleNone
+	if cfg.DefaultRole != "" {
+		role, err := authz.ParseRole(cfg.DefaultRole)
+		if err != nil {
+			return nil, authz.RoleNone, fmt.Errorf("RBAC_DEFAULT_ROLE: %w", err)
+		}
+		defaultRole = role
+	}
+
+	anonymous := authz.RoleNone
+	if transport == config.MCPTransportStdio && cfg.StdioRole != "" {
+		role, err := authz.ParseRole(cfg.StdioRole)
+		if err != nil {
+			return nil, authz.RoleNone, fmt.Errorf("RBAC_STDIO_ROLE: %w", err)
+		}
+		anonymous = role
+	}
+
+	policy := authz.NewPolicy(defaultRole, cfg.RolesClaim, cfg.GroupsClaim)
+	if cfg.PolicyFile != "" {
+		if err := policy.Load(cfg.PolicyFile); err != nil {
+			return nil, authz.RoleNone, fmt.Errorf("RBAC_POLICY_FILE: %w", err)
+		}
+	}
+
+	return policy, anonymous, nil
+}
+
+// newAuthenticator creates the authenticator of the HTTP_AUTH_MODE
+func newAuthenticator(ctx context.Context, cfg config.HTTPTransport, jwtCfg config.JWT) (auth.Authenticator, error) {
+	switch cfg.AuthMode {
+	case config.HTTPAuthToken:
+		return newTokens(cfg)
+	case config.HTTPAuthJWT:
+		return newJWT(ctx, jwtCfg)
+	default:
+		return nil, fmt.Errorf("unknown HTTP_AUTH_MODE: %s", cfg.AuthMode)
+	}
+}
+
+// newJWT loads the key set verifying the JWTs, the issuer and audience are required
+// so that tokens issued for other services are not accepted
+func newJWT(ctx context.Context, cfg config.JWT) (*auth.JWT, error) {
+
+	if cfg.JWKS == "" || cfg.Issuer == "" || cfg.Audience == "" {
+		return nil, errors.New("JWT_JWKS, JWT_ISSUER and JWT_AUDIENCE are required when HTTP_AUTH_MODE=jwt")
+	}
+
+	keys, err := auth.NewJWKS(ctx, cfg.JWKS, time.Duration(cfg.Refresh)*time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	return auth.NewJWT(keys, cfg.Issuer, cfg.Audience, time.Duration(cfg.Leeway)*time.Second), nil
+}
+
+// newTokens loads the bearer tokens accepted by the HTTP transports: those of HTTP_AUTH_TOKEN_FILE,
+// and HTTP_AUTH_TOKEN for the "default" principal unless it is left to its default next to a file.
+// The default token is refused unless the server only listens on localhost
+func newTokens(cfg config.HTTPTransport) (*auth.Tokens, error) {
+
+	tokens := auth.NewTokens()
+	if cfg.AuthTokenFile != "" {
+		if err := tokens.Load(cfg.AuthTokenFile); err != nil {
+			return nil, fmt.Errorf("HTTP_AUTH_TOKEN_FILE: %w", err)
+		}
+	}
+	if cfg.AuthToken != "" && (cfg.AuthTokenFile == "" || cfg.AuthToken != config.DefaultAuthToken) {
+		if err := tokens.Add("default", cfg.AuthToken); err != nil {
+			return nil, err
+		}
+	}
+
+	if tokens.Len() == 0 {
+		return nil, errors.New("no bearer token configured, set HTTP_AUTH_TOKEN or HTTP_AUTH_TOKEN_FILE")
+	}
+	if tokens.Contains(config.DefaultAuthToken) && !auth.IsLoopback(cfg.Host) {
+		return nil, fmt.Errorf("refusing the default token %q on HTTP_HOST %s, set HTTP_AUTH_TOKEN or HTTP_AUTH_TOKEN_FILE",
+			config.DefaultAuthToken, cfg.Host)
+	}
+
+	return tokens, nil
+}
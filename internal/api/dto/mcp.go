@@ -6,19 +6,94 @@ import "github.com/hungpdn/mcp2grule/internal/storage"
 type EvaluateIn struct {
 	Facts    Fact   `json:"facts" jsonschema:"Facts to be evaluated"`
 	RuleName string `json:"rule_name" jsonschema:"Name of the ruleset to be used for evaluation"`
+	Trace    bool   `json:"trace,omitempty" jsonschema:"Also return the rules that matched and fired and the facts each one changed"`
 }
 
 // EvaluateOut is the output structure for Evaluate method
 type EvaluateOut struct {
 	ModifiedFacts map[string]any `json:"modified_facts" jsonschema:"Modified facts after evaluation"`
+	Trace         *Trace         `json:"trace,omitempty" jsonschema:"Execution trace, only returned when requested"`
+}
+
+// EvaluateSetIn is the input structure for EvaluateSet method
+type EvaluateSetIn struct {
+	Facts     Fact     `json:"facts" jsonschema:"Facts to be evaluated"`
+	RuleNames []string `json:"rule_names,omitempty" jsonschema:"Names of the rulesets to evaluate together, exclusive with tag"`
+	Tag       string   `json:"tag,omitempty" jsonschema:"Evaluate together every ruleset carrying this tag, exclusive with rule_names"`
+	Trace     bool     `json:"trace,omitempty" jsonschema:"Also return the rules that matched and fired and the facts each one changed"`
+}
+
+// EvaluateSetOut is the output structure for EvaluateSet method
+type EvaluateSetOut struct {
+	ModifiedFacts map[string]any `json:"modified_facts" jsonschema:"Modified facts after evaluation"`
+	Rulesets      []string       `json:"rulesets" jsonschema:"Evaluated rulesets, highest salience first"`
+	Trace         *Trace         `json:"trace,omitempty" jsonschema:"Execution trace, only returned when requested"`
+}
+
+// EvaluateBatchIn is the input structure for EvaluateBatch method
+type EvaluateBatchIn struct {
+	RuleName string `json:"rule_name" jsonschema:"Name of the ruleset to be used for evaluation"`
+	Facts    []Fact `json:"facts" jsonschema:"Fact sets to be evaluated, each one independently"`
+}
+
+// EvaluateBatchOut is the output structure for EvaluateBatch method
+type EvaluateBatchOut struct {
+	Results []BatchResult `json:"results" jsonschema:"Results in the order of the input fact sets"`
+	Failed  int           `json:"failed" jsonschema:"Number of fact sets whose evaluation failed"`
+}
+
+// BatchResult is the evaluation result of a single fact set of a batch
+type BatchResult struct {
+	Index         int            `json:"index" jsonschema:"Position of the fact set in the input"`
+	ModifiedFacts map[string]any `json:"modified_facts" jsonschema:"Modified facts after evaluation, null when it failed"`
+	Error         string         `json:"error,omitempty" jsonschema:"Evaluation error of the fact set"`
+}
+
+// TryIn is the input structure for Try method
+type TryIn struct {
+	GRL   string `json:"grl" jsonschema:"The GRL content to evaluate, it is not stored"`
+	Facts Fact   `json:"facts" jsonschema:"Facts to be evaluated"`
+	Trace bool   `json:"trace,omitempty" jsonschema:"Also return the rules that matched and fired and the facts each one changed"`
+}
+
+// TryOut is the output structure for Try method
+type TryOut struct {
+	ModifiedFacts map[string]any `json:"modified_facts,omitempty" jsonschema:"Modified facts after evaluation, absent when the GRL does not compile"`
+	Errors        []CompileIssue `json:"errors,omitempty" jsonschema:"Compile errors of the GRL"`
+	Trace         *Trace         `json:"trace,omitempty" jsonschema:"Execution trace, only returned when requested"`
+}
+
+// Trace describes how the engine reached the modified facts
+type Trace struct {
+	Cycles int         `json:"cycles" jsonschema:"Number of cycles the engine ran"`
+	Fired  []FiredRule `json:"fired" jsonschema:"Rules fired, in execution order"`
+}
+
+// FiredRule is a rule executed by the engine in a cycle
+type FiredRule struct {
+	Cycle    int          `json:"cycle" jsonschema:"Cycle in which the rule fired"`
+	Rule     string       `json:"rule" jsonschema:"Name of the rule"`
+	Salience int          `json:"salience" jsonschema:"Salience of the rule, offset by the priority of its ruleset when rulesets are evaluated together"`
+	Matched  []string     `json:"matched" jsonschema:"Rules whose conditions matched in this cycle, the fired one has the highest salience"`
+	Changes  []FactChange `json:"changes" jsonschema:"Facts changed by the rule"`
+}
+
+// FactChange is a fact modified by a rule
+type FactChange struct {
+	Key    string `json:"key" jsonschema:"Name of the fact"`
+	Before any    `json:"before" jsonschema:"Value before the rule fired, null when unset"`
+	After  any    `json:"after" jsonschema:"Value after the rule fired"`
 }
 
 // CreateIn is the input structure for Create method
 type CreateIn struct {
-	Name        string `json:"name" jsonschema:"Name of the ruleset"`
-	Description string `json:"description" jsonschema:"Description of the ruleset"`
-	Salience    int    `json:"salience" jsonschema:"Priority of the rule"`
-	GRL         string `json:"grl" jsonschema:"The actual GRL content"`
+	Name        string         `json:"name" jsonschema:"Name of the ruleset"`
+	Description string         `json:"description" jsonschema:"Description of the ruleset"`
+	Salience    int            `json:"salience" jsonschema:"Priority of the ruleset when evaluated together with other rulesets"`
+	GRL         string         `json:"grl" jsonschema:"The actual GRL content"`
+	Tags        []string       `json:"tags,omitempty" jsonschema:"Labels selecting the rulesets evaluated together by grule.evaluate_set"`
+	FactSchema  map[string]any `json:"fact_schema,omitempty" jsonschema:"JSON Schema (draft 2020-12) of the facts, checked before every evaluation"`
+	Author      string         `json:"author,omitempty" jsonschema:"Author of the change, recorded in the revision history"`
 }
 
 // CreateOut is the output structure for Create method
@@ -26,17 +101,29 @@ type CreateOut struct {
 	ID string `json:"id" jsonschema:"ID of the created ruleset"`
 }
 
+// CompileIssue describes a single GRL compile error
+type CompileIssue struct {
+	Line    int    `json:"line" jsonschema:"Line of the error counted from 1, 0 when unknown"`
+	Column  int    `json:"column" jsonschema:"Column of the error counted from 1, 0 when unknown"`
+	Message string `json:"message" jsonschema:"Description of the error"`
+}
+
 // UpdateIn is the input structure for Update method
 type UpdateIn struct {
-	Name        string `json:"name" jsonschema:"Name of the ruleset"`
-	Description string `json:"description" jsonschema:"Description of the ruleset"`
-	Salience    int    `json:"salience" jsonschema:"Priority of the rule"`
-	GRL         string `json:"grl" jsonschema:"The actual GRL content"`
+	Name            string         `json:"name" jsonschema:"Name of the ruleset"`
+	Description     string         `json:"description" jsonschema:"Description of the ruleset"`
+	Salience        int            `json:"salience" jsonschema:"Priority of the ruleset when evaluated together with other rulesets"`
+	GRL             string         `json:"grl" jsonschema:"The actual GRL content"`
+	Tags            []string       `json:"tags,omitempty" jsonschema:"Labels selecting the rulesets evaluated together by grule.evaluate_set, omit to keep the current ones"`
+	FactSchema      map[string]any `json:"fact_schema,omitempty" jsonschema:"JSON Schema (draft 2020-12) of the facts, omit to keep the current one, an empty object removes it"`
+	Author          string         `json:"author,omitempty" jsonschema:"Author of the change, recorded in the revision history"`
+	ExpectedVersion int            `json:"expected_version,omitempty" jsonschema:"Version the change is based on, the update is rejected if the ruleset changed since"`
 }
 
 // UpdateOut is the output structure for Update method
 type UpdateOut struct {
 	Success bool `json:"success" jsonschema:"Indicates if the update was successful"`
+	Version int  `json:"version" jsonschema:"Version of the ruleset after the update"`
 }
 
 // DeleteIn is the input structure for Delete method
@@ -63,3 +150,106 @@ type GetByNameOut struct {
 type GetAllOut struct {
 	Rulesets []storage.Ruleset `json:"rulesets" jsonschema:"List of all rulesets"`
 }
+
+// SchemaIn is the input structure for Schema method
+type SchemaIn struct {
+	Name string `json:"name" jsonschema:"Name of the ruleset"`
+}
+
+// SchemaOut is the output structure for Schema method
+type SchemaOut struct {
+	Name        string         `json:"name" jsonschema:"Name of the ruleset"`
+	FactSchema  map[string]any `json:"fact_schema,omitempty" jsonschema:"JSON Schema of the facts, absent when the ruleset accepts any facts"`
+	InputSchema map[string]any `json:"input_schema" jsonschema:"JSON Schema of the grule.evaluate arguments for this ruleset"`
+}
+
+// HistoryIn is the input structure for History method
+type HistoryIn struct {
+	Name string `json:"name" jsonschema:"Name of the ruleset"`
+}
+
+// HistoryOut is the output structure for History method
+type HistoryOut struct {
+	Revisions []storage.Revision `json:"revisions" jsonschema:"Revisions of the ruleset, oldest first"`
+}
+
+// RollbackIn is the input structure for Rollback method
+type RollbackIn struct {
+	Name     string `json:"name" jsonschema:"Name of the ruleset"`
+	Revision int    `json:"revision" jsonschema:"Revision number to restore"`
+	Author   string `json:"author,omitempty" jsonschema:"Author of the rollback, recorded in the revision history"`
+}
+
+// RollbackOut is the output structure for Rollback method
+type RollbackOut struct {
+	Success bool `json:"success" jsonschema:"Indicates if the rollback was successful"`
+}
+
+// EvaluatePipelineIn is the input structure for EvaluatePipeline method
+type EvaluatePipelineIn struct {
+	Facts    Fact   `json:"facts" jsonschema:"Facts to be evaluated"`
+	Pipeline string `json:"pipeline" jsonschema:"Name of the pipeline to be used for evaluation"`
+	Trace    bool   `json:"trace,omitempty" jsonschema:"Also return the rules that matched and fired in each stage"`
+}
+
+// EvaluatePipelineOut is the output structure for EvaluatePipeline method
+type EvaluatePipelineOut struct {
+	ModifiedFacts map[string]any `json:"modified_facts" jsonschema:"Modified facts after the last executed stage"`
+	Stages        []StageResult  `json:"stages" jsonschema:"Executed stages, in order"`
+}
+
+// StageResult describes a stage executed by a pipeline
+type StageResult struct {
+	Ruleset string `json:"ruleset" jsonschema:"Name of the ruleset evaluated by the stage"`
+	Stopped bool   `json:"stopped,omitempty" jsonschema:"Indicates that the stop condition of the stage held and the pipeline ended"`
+	Trace   *Trace `json:"trace,omitempty" jsonschema:"Execution trace of the stage, only returned when requested"`
+}
+
+// CreatePipelineIn is the input structure for CreatePipeline method
+type CreatePipelineIn struct {
+	Name        string          `json:"name" jsonschema:"Name of the pipeline"`
+	Description string          `json:"description" jsonschema:"Description of the pipeline"`
+	Stages      []storage.Stage `json:"stages" jsonschema:"Rulesets to evaluate in order, each with an optional stop condition"`
+}
+
+// CreatePipelineOut is the output structure for CreatePipeline method
+type CreatePipelineOut struct {
+	ID string `json:"id" jsonschema:"ID of the created pipeline"`
+}
+
+// UpdatePipelineIn is the input structure for UpdatePipeline method
+type UpdatePipelineIn struct {
+	Name        string          `json:"name" jsonschema:"Name of the pipeline"`
+	Description string          `json:"description" jsonschema:"Description of the pipeline"`
+	Stages      []storage.Stage `json:"stages" jsonschema:"Rulesets to evaluate in order, each with an optional stop condition"`
+}
+
+// UpdatePipelineOut is the output structure for UpdatePipeline method
+type UpdatePipelineOut struct {
+	Success bool `json:"success" jsonschema:"Indicates if the update was successful"`
+}
+
+// DeletePipelineIn is the input structure for DeletePipeline method
+type DeletePipelineIn struct {
+	Name string `json:"name" jsonschema:"Name of the pipeline to be deleted"`
+}
+
+// DeletePipelineOut is the output structure for DeletePipeline method
+type DeletePipelineOut struct {
+	Success bool `json:"success" jsonschema:"Indicates if the deletion was successful"`
+}
+
+// GetPipelineIn is the input structure for GetPipeline method
+type GetPipelineIn struct {
+	Name string `json:"name" jsonschema:"Name of the pipeline to retrieve"`
+}
+
+// GetPipelineOut is the output structure for GetPipeline method
+type GetPipelineOut struct {
+	Pipeline storage.Pipeline `json:"pipeline" jsonschema:"Retrieved pipeline"`
+}
+
+// GetAllPipelinesOut is the output structure for GetAllPipelines method
+type GetAllPipelinesOut struct {
+	Pipelines []storage.Pipeline `json:"pipelines" jsonschema:"List of all pipelines"`
+}
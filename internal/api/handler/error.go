@@ -0,0 +1,119 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/hungpdn/mcp2grule/internal/api/dto"
+	"github.com/hungpdn/mcp2grule/internal/grule"
+	"github.com/hungpdn/mcp2grule/internal/pkg/authz"
+	"github.com/hungpdn/mcp2grule/internal/pkg/middleware"
+	"github.com/hungpdn/mcp2grule/internal/storage"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// ErrorCode is the machine-readable code of a tool error, clients may branch on it
+type ErrorCode string
+
+const (
+	CodeNotFound       ErrorCode = "not_found"
+	CodeAlreadyExists  ErrorCode = "already_exists"
+	CodeConflict       ErrorCode = "conflict"
+	CodeForbidden      ErrorCode = "forbidden"
+	CodeRateLimited    ErrorCode = "rate_limited"
+	CodeInvalidInput   ErrorCode = "invalid_input"
+	CodeCompileError   ErrorCode = "compile_error"
+	CodeInvalidFacts   ErrorCode = "invalid_facts"
+	CodeExecutionError ErrorCode = "execution_error"
+	CodeCanceled       ErrorCode = "canceled"
+	CodeDatabaseError  ErrorCode = "database_error"
+	CodeInternal       ErrorCode = "internal"
+)
+
+// ToolError is the body of a tool error result
+type ToolError struct {
+	Code    ErrorCode          `json:"code"`
+	Message string             `json:"message"`
+	Errors  []dto.CompileIssue `json:"errors,omitempty"` // Compile errors of the GRL, with their position
+	Issues  []string           `json:"issues,omitempty"` // Unknown and missing facts
+
+	RetryAfter float64 `json:"retry_after,omitempty"` // Seconds to wait before calling again when rate limited
+}
+
+// NewToolError maps an error of the service to a tool error
+func NewToolError(err error) ToolError {
+
+	out := ToolError{Code: errorCode(err), Message: err.Error()}
+
+	var compileErr *grule.CompileError
+	if errors.As(err, &compileErr) {
+		out.Errors = compileErr.Errors
+	}
+	var factsErr *grule.FactsError
+	if errors.As(err, &factsErr) {
+		out.Issues = factsErr.Issues
+	}
+	var rateErr *middleware.RateLimitError
+	if errors.As(err, &rateErr) {
+		out.RetryAfter = rateErr.RetryAfter.Seconds()
+	}
+
+	return out
+}
+
+// errorCode returns the code of an error, the most specific error wins
+func errorCode(err error) ErrorCode {
+
+	var (
+		compileErr *grule.CompileError
+		factsErr   *grule.FactsError
+		rateErr    *middleware.RateLimitError
+	)
+
+	switch {
+	case errors.As(err, &compileErr):
+		return CodeCompileError
+	case errors.As(err, &factsErr):
+		return CodeInvalidFacts
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return CodeCanceled
+	case errors.As(err, &rateErr):
+		return CodeRateLimited
+	case errors.Is(err, authz.ErrForbidden):
+		return CodeForbidden
+	case errors.Is(err, grule.ErrExecution):
+		return CodeExecutionError
+	case errors.Is(err, storage.ErrNotFound):
+		return CodeNotFound
+	case errors.Is(err, storage.ErrAlreadyExists):
+		return CodeAlreadyExists
+	case errors.Is(err, storage.ErrConflict):
+		return CodeConflict
+	case errors.Is(err, storage.ErrInvalidInput):
+		return CodeInvalidInput
+	case errors.Is(err, storage.ErrDatabase):
+		return CodeDatabaseError
+	default:
+		return CodeInternal
+	}
+}
+
+// ErrorResult reports an error as a tool error rather than a protocol error, so that
+// the model sees it and can correct the call. The text is the JSON of the ToolError
+// and the code is repeated in the metadata of the result
+func ErrorResult(err error) *mcp.CallToolResult {
+
+	toolErr := NewToolError(err)
+	text, _ := json.Marshal(toolErr)
+
+	return &mcp.CallToolResult{
+		Meta: mcp.Meta{"error_code": toolErr.Code},
+		Content: []mcp.Content{
+			&mcp.TextContent{
+				Text: string(text),
+			},
+		},
+		IsError: true,
+	}
+}
@@ -0,0 +1,116 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/hungpdn/mcp2grule/internal/api/dto"
+	"github.com/hungpdn/mcp2grule/internal/grule"
+	"github.com/hungpdn/mcp2grule/internal/pkg/authz"
+	"github.com/hungpdn/mcp2grule/internal/pkg/middleware"
+	"github.com/hungpdn/mcp2grule/internal/storage"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestNewToolError(t *testing.T) {
+
+	compileErr := &grule.CompileError{Errors: []dto.CompileIssue{{Line: 1, Column: 38, Message: "missing ';' at '}'"}}}
+	factsErr := &grule.FactsError{Ruleset: "loan", Issues: []string{`missing required fact "amount"`}, Err: errors.New("validating root")}
+
+	tests := []struct {
+		name string
+		err  error
+		want ErrorCode
+	}{
+		{"not found", fmt.Errorf("ruleset loan: %w", storage.ErrNotFound), CodeNotFound},
+		{"already exists", storage.ErrAlreadyExists, CodeAlreadyExists},
+		{"conflict", fmt.Errorf("%w: ruleset loan is at version 3", storage.ErrConflict), CodeConflict},
+		{"invalid input", fmt.Errorf("%w: a pipeline needs at least one stage", storage.ErrInvalidInput), CodeInvalidInput},
+		{"database", fmt.Errorf("%w: disk I/O error", storage.ErrDatabase), CodeDatabaseError},
+		{"forbidden", fmt.Errorf("%w: alice may not update loan", authz.ErrForbidden), CodeForbidden},
+		{"rate limited", &middleware.RateLimitError{Budget: middleware.BudgetWrite, RetryAfter: 1500 * time.Millisecond}, CodeRateLimited},
+		{"compile error", compileErr, CodeCompileError},
+		{"compile error wins over invalid input", fmt.Errorf("%w: ruleset loan: %w", storage.ErrInvalidInput, compileErr), CodeCompileError},
+		{"invalid facts", factsErr, CodeInvalidFacts},
+		{"execution error", fmt.Errorf("%w: cycle limit reached", grule.ErrExecution), CodeExecutionError},
+		{"canceled", fmt.Errorf("stage 1 (loan): %w", context.Canceled), CodeCanceled},
+		{"deadline wins over execution error", fmt.Errorf("%w: %w", grule.ErrExecution, context.DeadlineExceeded), CodeCanceled},
+		{"unknown error", errors.New("boom"), CodeInternal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NewToolError(tt.err)
+			if got.Code != tt.want {
+				t.Fatalf("code %s, want %s", got.Code, tt.want)
+			}
+			if got.Message != tt.err.Error() {
+				t.Fatalf("message %q, want %q", got.Message, tt.err.Error())
+			}
+		})
+	}
+}
+
+func TestErrorResult(t *testing.T) {
+
+	tests := []struct {
+		name  string
+		err   error
+		check func(t *testing.T, toolErr ToolError)
+	}{
+		{
+			name: "compile errors",
+			err:  &grule.CompileError{Errors: []dto.CompileIssue{{Line: 2, Column: 5, Message: "mismatched input"}}},
+			check: func(t *testing.T, toolErr ToolError) {
+				if len(toolErr.Errors) != 1 || toolErr.Errors[0].Line != 2 || toolErr.Errors[0].Column != 5 {
+					t.Fatalf("errors %+v, want the compile issue", toolErr.Errors)
+				}
+			},
+		},
+		{
+			name: "fact issues",
+			err:  &grule.FactsError{Ruleset: "loan", Issues: []string{`unknown fact "score"`}, Err: errors.New("validating root")},
+			check: func(t *testing.T, toolErr ToolError) {
+				if len(toolErr.Issues) != 1 || toolErr.Issues[0] != `unknown fact "score"` {
+					t.Fatalf("issues %q, want the unknown fact", toolErr.Issues)
+				}
+			},
+		},
+		{
+			name: "retry after",
+			err:  &middleware.RateLimitError{Budget: middleware.BudgetRead, RetryAfter: 2500 * time.Millisecond},
+			check: func(t *testing.T, toolErr ToolError) {
+				if toolErr.RetryAfter != 2.5 {
+					t.Fatalf("retry after %v, want 2.5", toolErr.RetryAfter)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := ErrorResult(tt.err)
+			if !res.IsError {
+				t.Fatal("result is not an error")
+			}
+			if len(res.Content) != 1 {
+				t.Fatalf("%d contents, want 1", len(res.Content))
+			}
+			text, ok := res.Content[0].(*mcp.TextContent)
+			if !ok {
+				t.Fatalf("content %T, want text", res.Content[0])
+			}
+
+			var toolErr ToolError
+			if err := json.Unmarshal([]byte(text.Text), &toolErr); err != nil {
+				t.Fatal(err)
+			}
+			if res.Meta["error_code"] != toolErr.Code || toolErr.Code != errorCode(tt.err) {
+				t.Fatalf("error_code %v and code %s, want both %s", res.Meta["error_code"], toolErr.Code, errorCode(tt.err))
+			}
+			tt.check(t, toolErr)
+		})
+	}
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/hungpdn/mcp2grule/internal/api/dto"
 	"github.com/hungpdn/mcp2grule/internal/grule"
+	"github.com/hungpdn/mcp2grule/internal/pkg/logger"
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 )
 
@@ -28,7 +29,56 @@ func (h *MCPHandler) Evaluate(
 
 	out, err := h.grule.Evaluate(ctx, in)
 	if err != nil {
-		return nil, nil, err
+		return ErrorResult(err), nil, nil
+	}
+
+	text, _ := json.Marshal(out)
+
+	result := &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{
+				Text: string(text),
+			},
+		},
+	}
+	return result, out, nil
+}
+
+// EvaluateSet handles the EvaluateSet API call
+func (h *MCPHandler) EvaluateSet(
+	ctx context.Context,
+	req *mcp.CallToolRequest,
+	in dto.EvaluateSetIn,
+) (*mcp.CallToolResult, *dto.EvaluateSetOut, error) {
+
+	out, err := h.grule.EvaluateSet(ctx, in)
+	if err != nil {
+		return ErrorResult(err), nil, nil
+	}
+
+	text, _ := json.Marshal(out)
+
+	result := &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{
+				Text: string(text),
+			},
+		},
+	}
+	return result, out, nil
+}
+
+// EvaluateBatch handles the EvaluateBatch API call,
+// progress is notified when the client sent a progress token
+func (h *MCPHandler) EvaluateBatch(
+	ctx context.Context,
+	req *mcp.CallToolRequest,
+	in dto.EvaluateBatchIn,
+) (*mcp.CallToolResult, *dto.EvaluateBatchOut, error) {
+
+	out, err := h.grule.EvaluateBatch(ctx, in, progress(ctx, req))
+	if err != nil {
+		return ErrorResult(err), nil, nil
 	}
 
 	text, _ := json.Marshal(out)
@@ -52,7 +102,7 @@ func (h *MCPHandler) Create(
 
 	out, err := h.grule.Create(ctx, in)
 	if err != nil {
-		return nil, nil, err
+		return ErrorResult(err), nil, nil
 	}
 
 	text, _ := json.Marshal(out)
@@ -76,7 +126,7 @@ func (h *MCPHandler) Update(
 
 	out, err := h.grule.Update(ctx, in.Name, in)
 	if err != nil {
-		return nil, nil, err
+		return ErrorResult(err), nil, nil
 	}
 
 	text, _ := json.Marshal(out)
@@ -100,7 +150,7 @@ func (h *MCPHandler) Delete(
 
 	out, err := h.grule.Delete(ctx, in.Name)
 	if err != nil {
-		return nil, nil, err
+		return ErrorResult(err), nil, nil
 	}
 
 	text, _ := json.Marshal(out)
@@ -124,7 +174,7 @@ func (h *MCPHandler) GetAll(
 
 	out, err := h.grule.GetAll(ctx)
 	if err != nil {
-		return nil, nil, err
+		return ErrorResult(err), nil, nil
 	}
 
 	text, _ := json.Marshal(out)
@@ -148,7 +198,31 @@ func (h *MCPHandler) GetByName(
 
 	out, err := h.grule.GetByName(ctx, in.Name)
 	if err != nil {
-		return nil, nil, err
+		return ErrorResult(err), nil, nil
+	}
+
+	text, _ := json.Marshal(out)
+
+	result := &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{
+				Text: string(text),
+			},
+		},
+	}
+	return result, out, nil
+}
+
+// Schema handles the Schema API call
+func (h *MCPHandler) Schema(
+	ctx context.Context,
+	req *mcp.CallToolRequest,
+	in dto.SchemaIn,
+) (*mcp.CallToolResult, *dto.SchemaOut, error) {
+
+	out, err := h.grule.Schema(ctx, in.Name)
+	if err != nil {
+		return ErrorResult(err), nil, nil
 	}
 
 	text, _ := json.Marshal(out)
@@ -162,3 +236,248 @@ func (h *MCPHandler) GetByName(
 	}
 	return result, out, nil
 }
+
+// History handles the History API call
+func (h *MCPHandler) History(
+	ctx context.Context,
+	req *mcp.CallToolRequest,
+	in dto.HistoryIn,
+) (*mcp.CallToolResult, *dto.HistoryOut, error) {
+
+	out, err := h.grule.History(ctx, in.Name)
+	if err != nil {
+		return ErrorResult(err), nil, nil
+	}
+
+	text, _ := json.Marshal(out)
+
+	result := &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{
+				Text: string(text),
+			},
+		},
+	}
+	return result, out, nil
+}
+
+// Rollback handles the Rollback API call
+func (h *MCPHandler) Rollback(
+	ctx context.Context,
+	req *mcp.CallToolRequest,
+	in dto.RollbackIn,
+) (*mcp.CallToolResult, *dto.RollbackOut, error) {
+
+	out, err := h.grule.Rollback(ctx, in.Name, in)
+	if err != nil {
+		return ErrorResult(err), nil, nil
+	}
+
+	text, _ := json.Marshal(out)
+
+	result := &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{
+				Text: string(text),
+			},
+		},
+	}
+	return result, out, nil
+}
+
+// Try handles the Try API call
+func (h *MCPHandler) Try(
+	ctx context.Context,
+	req *mcp.CallToolRequest,
+	in dto.TryIn,
+) (*mcp.CallToolResult, *dto.TryOut, error) {
+
+	out, err := h.grule.Try(ctx, in)
+	if err != nil {
+		return ErrorResult(err), nil, nil
+	}
+
+	text, _ := json.Marshal(out)
+
+	result := &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{
+				Text: string(text),
+			},
+		},
+	}
+	return result, out, nil
+}
+
+// EvaluatePipeline handles the EvaluatePipeline API call
+func (h *MCPHandler) EvaluatePipeline(
+	ctx context.Context,
+	req *mcp.CallToolRequest,
+	in dto.EvaluatePipelineIn,
+) (*mcp.CallToolResult, *dto.EvaluatePipelineOut, error) {
+
+	out, err := h.grule.EvaluatePipeline(ctx, in)
+	if err != nil {
+		return ErrorResult(err), nil, nil
+	}
+
+	text, _ := json.Marshal(out)
+
+	result := &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{
+				Text: string(text),
+			},
+		},
+	}
+	return result, out, nil
+}
+
+// CreatePipeline handles the CreatePipeline API call
+func (h *MCPHandler) CreatePipeline(
+	ctx context.Context,
+	req *mcp.CallToolRequest,
+	in dto.CreatePipelineIn,
+) (*mcp.CallToolResult, *dto.CreatePipelineOut, error) {
+
+	out, err := h.grule.CreatePipeline(ctx, in)
+	if err != nil {
+		return ErrorResult(err), nil, nil
+	}
+
+	text, _ := json.Marshal(out)
+
+	result := &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{
+				Text: string(text),
+			},
+		},
+	}
+	return result, out, nil
+}
+
+// UpdatePipeline handles the UpdatePipeline API call
+func (h *MCPHandler) UpdatePipeline(
+	ctx context.Context,
+	req *mcp.CallToolRequest,
+	in dto.UpdatePipelineIn,
+) (*mcp.CallToolResult, *dto.UpdatePipelineOut, error) {
+
+	out, err := h.grule.UpdatePipeline(ctx, in.Name, in)
+	if err != nil {
+		return ErrorResult(err), nil, nil
+	}
+
+	text, _ := json.Marshal(out)
+
+	result := &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{
+				Text: string(text),
+			},
+		},
+	}
+	return result, out, nil
+}
+
+// DeletePipeline handles the DeletePipeline API call
+func (h *MCPHandler) DeletePipeline(
+	ctx context.Context,
+	req *mcp.CallToolRequest,
+	in dto.DeletePipelineIn,
+) (*mcp.CallToolResult, *dto.DeletePipelineOut, error) {
+
+	out, err := h.grule.DeletePipeline(ctx, in.Name)
+	if err != nil {
+		return ErrorResult(err), nil, nil
+	}
+
+	text, _ := json.Marshal(out)
+
+	result := &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{
+				Text: string(text),
+			},
+		},
+	}
+	return result, out, nil
+}
+
+// GetAllPipelines handles the GetAllPipelines API call
+func (h *MCPHandler) GetAllPipelines(
+	ctx context.Context,
+	req *mcp.CallToolRequest,
+	_ any,
+) (*mcp.CallToolResult, *dto.GetAllPipelinesOut, error) {
+
+	out, err := h.grule.GetAllPipelines(ctx)
+	if err != nil {
+		return ErrorResult(err), nil, nil
+	}
+
+	text, _ := json.Marshal(out)
+
+	result := &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{
+				Text: string(text),
+			},
+		},
+	}
+	return result, out, nil
+}
+
+// GetPipeline handles the GetPipeline API call
+func (h *MCPHandler) GetPipeline(
+	ctx context.Context,
+	req *mcp.CallToolRequest,
+	in dto.GetPipelineIn,
+) (*mcp.CallToolResult, *dto.GetPipelineOut, error) {
+
+	out, err := h.grule.GetPipeline(ctx, in.Name)
+	if err != nil {
+		return ErrorResult(err), nil, nil
+	}
+
+	text, _ := json.Marshal(out)
+
+	result := &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{
+				Text: string(text),
+			},
+		},
+	}
+	return result, out, nil
+}
+
+// progressSteps is the number of progress notifications sent for a long running call
+const progressSteps = 20
+
+// progress returns a grule.Progress sending MCP progress notifications,
+// or nil when the client did not ask for them
+func progress(ctx context.Context, req *mcp.CallToolRequest) grule.Progress {
+
+	token := req.Params.GetProgressToken()
+	if token == nil || req.Session == nil {
+		return nil
+	}
+
+	return func(done, total int) {
+		step := max(total/progressSteps, 1)
+		if done%step != 0 && done != total {
+			return
+		}
+
+		err := req.Session.NotifyProgress(ctx, &mcp.ProgressNotificationParams{
+			ProgressToken: token,
+			Progress:      float64(done),
+			Total:         float64(total),
+		})
+		if err != nil {
+			logger.WithContext(ctx).Warnf("Failed to notify progress: %v", err)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package handler
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/hungpdn/mcp2grule/internal/storage"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// grlGuide is the GRL syntax guidance embedded in every prompt
+//
+//go:embed prompts/grl.md
+var grlGuide string
+
+// WriteRule asks to write a ruleset from a natural-language policy and an example of the facts,
+// or to extend an existing ruleset
+func (h *MCPHandler) WriteRule(ctx context.Context, req *mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+
+	args := req.Params.Arguments
+	policy := strings.TrimSpace(args["policy"])
+	if policy == "" {
+		return nil, fmt.Errorf("%w: policy is required", storage.ErrInvalidInput)
+	}
+
+	var b strings.Builder
+	b.WriteString(grlGuide)
+	b.WriteString("\n# Task\n\nWrite the GRL of a ruleset implementing this policy:\n\n")
+	b.WriteString(policy)
+	b.WriteString("\n")
+
+	if facts := strings.TrimSpace(args["facts"]); facts != "" {
+		fmt.Fprintf(&b, "\nThe facts look like this example:\n\n```json\n%s\n```\n", facts)
+	}
+
+	if name := strings.TrimSpace(args["ruleset"]); name != "" {
+		rule, err := h.grule.GetByName(ctx, name)
+		if err != nil {
+			return nil, fmt.Errorf("ruleset %s: %w", name, err)
+		}
+		b.WriteString("\nExtend this existing ruleset, keep its rules unless the policy contradicts them:\n\n")
+		writeRuleset(&b, rule.Ruleset)
+		fmt.Fprintf(&b, "\nAnswer with the complete GRL, then save it with grule.update (name %q, expected_version %d) "+
+			"after checking it with grule.try.\n", rule.Ruleset.Name, rule.Ruleset.Version)
+	} else {
+		b.WriteString("\nAnswer with the complete GRL, then check it with grule.try and save it with grule.create.\n")
+	}
+
+	return promptResult("Write a GRL ruleset from a policy", b.String()), nil
+}
+
+// ExplainRule asks to explain a stored ruleset in plain language
+func (h *MCPHandler) ExplainRule(ctx context.Context, req *mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+
+	name := strings.TrimSpace(req.Params.Arguments["ruleset"])
+	if name == "" {
+		return nil, fmt.Errorf("%w: ruleset is required", storage.ErrInvalidInput)
+	}
+
+	rule, err := h.grule.GetByName(ctx, name)
+	if err != nil {
+		return nil, fmt.Errorf("ruleset %s: %w", name, err)
+	}
+
+	var b strings.Builder
+	b.WriteString(grlGuide)
+	b.WriteString("\n# Task\n\nExplain this ruleset in plain language:\n\n")
+	writeRuleset(&b, rule.Ruleset)
+	b.WriteString("\nFor every rule, say when it fires and which facts it sets, in the order the salience makes them fire. " +
+		"Then point out the facts the ruleset reads, any rule that can never fire or fire forever, and conflicting rules.\n")
+
+	return promptResult(fmt.Sprintf("Explain the %s ruleset", rule.Ruleset.Name), b.String()), nil
+}
+
+// FixRule asks to fix GRL that does not compile, either a draft or a stored ruleset
+func (h *MCPHandler) FixRule(ctx context.Context, req *mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+
+	args := req.Params.Arguments
+	compileErr := strings.TrimSpace(args["error"])
+	if compileErr == "" {
+		return nil, fmt.Errorf("%w: error is required", storage.ErrInvalidInput)
+	}
+
+	var b strings.Builder
+	b.WriteString(grlGuide)
+	b.WriteString("\n# Task\n\nFix the GRL below so that it compiles, keep its intent and change as little as possible.\n\n")
+
+	grl := strings.TrimSpace(args["grl"])
+	name := strings.TrimSpace(args["ruleset"])
+	switch {
+	case grl != "":
+		fmt.Fprintf(&b, "```grl\n%s\n```\n", grl)
+	case name != "":
+		rule, err := h.grule.GetByName(ctx, name)
+		if err != nil {
+			return nil, fmt.Errorf("ruleset %s: %w", name, err)
+		}
+		writeRuleset(&b, rule.Ruleset)
+	default:
+		return nil, fmt.Errorf("%w: one of grl or ruleset is required", storage.ErrInvalidInput)
+	}
+
+	fmt.Fprintf(&b, "\nThe compiler reported:\n\n```\n%s\n```\n", compileErr)
+	b.WriteString("\nAnswer with the complete fixed GRL and explain each change in one line. Check it with grule.try before saving it.\n")
+
+	return promptResult("Fix GRL that does not compile", b.String()), nil
+}
+
+// writeRuleset writes the GRL of a ruleset with what it declares about its facts
+func writeRuleset(b *strings.Builder, rule storage.Ruleset) {
+
+	fmt.Fprintf(b, "Ruleset %q (version %d, salience %d)", rule.Name, rule.Version, rule.Salience)
+	if rule.Description != "" {
+		fmt.Fprintf(b, ": %s", rule.Description)
+	}
+	b.WriteString("\n\n")
+	fmt.Fprintf(b, "```grl\n%s\n```\n", strings.TrimSpace(rule.GRL))
+
+	if rule.FactSchema != nil {
+		schema, _ := json.MarshalIndent(rule.FactSchema, "", "  ")
+		fmt.Fprintf(b, "\nThe facts must match this JSON Schema:\n\n```json\n%s\n```\n", schema)
+	}
+}
+
+// promptResult wraps the text of a prompt into a single user message
+func promptResult(description, text string) *mcp.GetPromptResult {
+
+	return &mcp.GetPromptResult{
+		Description: description,
+		Messages: []*mcp.PromptMessage{
+			{
+				Role: "user",
+				Content: &mcp.TextContent{
+					Text: text,
+				},
+			},
+		},
+	}
+}
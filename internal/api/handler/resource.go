@@ -0,0 +1,92 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/hungpdn/mcp2grule/internal/storage"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// RulesetURIPrefix prefixes the URI of the resource of every ruleset
+const RulesetURIPrefix = "grule://rulesets/"
+
+// RulesetURI returns the URI of the resource of a ruleset, e.g. grule://rulesets/loan_eligibility
+func RulesetURI(name string) string {
+	return RulesetURIPrefix + url.PathEscape(name)
+}
+
+// RulesetTemplate describes the resource template matching the resource of any ruleset
+func RulesetTemplate() *mcp.ResourceTemplate {
+	return &mcp.ResourceTemplate{
+		Name:        "ruleset",
+		URITemplate: RulesetURIPrefix + "{name}",
+		Description: "GRL of a rule set as text, followed by its metadata as JSON",
+	}
+}
+
+// RulesetResource describes the resource of a ruleset
+func RulesetResource(rule storage.Ruleset) *mcp.Resource {
+
+	description := rule.Description
+	if description == "" {
+		description = fmt.Sprintf("GRL and metadata of the %s rule set", rule.Name)
+	}
+
+	return &mcp.Resource{
+		Name:        rule.Name,
+		URI:         RulesetURI(rule.Name),
+		Description: description,
+	}
+}
+
+// ReadRuleset reads the resource of a ruleset, its contents are the GRL as text
+// and the metadata of the ruleset as JSON
+func (h *MCPHandler) ReadRuleset(ctx context.Context, req *mcp.ReadResourceRequest) (*mcp.ReadResourceResult, error) {
+
+	uri := req.Params.URI
+	name, err := url.PathUnescape(strings.TrimPrefix(uri, RulesetURIPrefix))
+	if err != nil || !strings.HasPrefix(uri, RulesetURIPrefix) || name == "" {
+		return nil, mcp.ResourceNotFoundError(uri)
+	}
+
+	out, err := h.grule.GetByName(ctx, name)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, mcp.ResourceNotFoundError(uri)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// the metadata is the ruleset without its GRL, already given as text
+	b, err := json.Marshal(out.Ruleset)
+	if err != nil {
+		return nil, err
+	}
+	metadata := map[string]any{}
+	if err := json.Unmarshal(b, &metadata); err != nil {
+		return nil, err
+	}
+	delete(metadata, "grl")
+	text, _ := json.Marshal(metadata)
+
+	result := &mcp.ReadResourceResult{
+		Contents: []*mcp.ResourceContents{
+			{
+				URI:      uri,
+				MIMEType: "text/plain",
+				Text:     out.Ruleset.GRL,
+			},
+			{
+				URI:      uri,
+				MIMEType: "application/json",
+				Text:     string(text),
+			},
+		},
+	}
+	return result, nil
+}
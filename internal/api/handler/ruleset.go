@@ -0,0 +1,133 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/google/jsonschema-go/jsonschema"
+	"github.com/hungpdn/mcp2grule/internal/api/dto"
+	"github.com/hungpdn/mcp2grule/internal/grule"
+	"github.com/hungpdn/mcp2grule/internal/pkg/authz"
+	"github.com/hungpdn/mcp2grule/internal/storage"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// RulesetToolPrefix prefixes the name of the dedicated tool of every ruleset
+const RulesetToolPrefix = "rule."
+
+// toolNameInvalid matches the characters not allowed in MCP tool names
+var toolNameInvalid = regexp.MustCompile(`[^a-z0-9_-]+`)
+
+// RulesetToolName returns the name of the dedicated tool of a ruleset, e.g. rule.loan_eligibility
+func RulesetToolName(name string) string {
+	return RulesetToolPrefix + toolNameInvalid.ReplaceAllString(strings.ToLower(name), "_")
+}
+
+// RulesetTool describes the dedicated tool of a ruleset, its input is the facts
+// and its input schema the fact schema of the ruleset
+func RulesetTool(rule storage.Ruleset) (*mcp.Tool, error) {
+
+	schema := &jsonschema.Schema{Type: "object"}
+	if rule.FactSchema != nil {
+		b, err := json.Marshal(rule.FactSchema)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(b, schema); err != nil {
+			return nil, err
+		}
+		if schema.Type == "" {
+			schema.Type = "object"
+		}
+	}
+
+	description := rule.Description
+	if description == "" {
+		description = fmt.Sprintf("Evaluate facts against the %s rule set", rule.Name)
+	}
+
+	return &mcp.Tool{
+		Name:        RulesetToolName(rule.Name),
+		Title:       "Evaluate " + rule.Name,
+		Description: description,
+		InputSchema: schema,
+	}, nil
+}
+
+// OnRulesetChange registers fn to be called after every ruleset change
+func (h *MCPHandler) OnRulesetChange(fn func(grule.Change)) {
+	h.grule.Subscribe(fn)
+}
+
+// Rulesets lists every stored ruleset, whoever may read it, for the server to register
+func (h *MCPHandler) Rulesets(ctx context.Context) ([]storage.Ruleset, error) {
+
+	out, err := h.grule.GetAll(authz.AsServer(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	return out.Rulesets, nil
+}
+
+// Readable returns the names of the rulesets the principal of the request may read,
+// none when its role does not allow reading at all
+func (h *MCPHandler) Readable(ctx context.Context) (map[string]bool, error) {
+
+	out, err := h.grule.GetAll(ctx)
+	if errors.Is(err, authz.ErrForbidden) {
+		return map[string]bool{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	names := make(map[string]bool, len(out.Rulesets))
+	for _, rule := range out.Rulesets {
+		names[rule.Name] = true
+	}
+
+	return names, nil
+}
+
+// EvaluateRuleset returns the handler of the dedicated tool of a ruleset,
+// it evaluates the facts given as arguments like grule.evaluate
+func (h *MCPHandler) EvaluateRuleset(name string) mcp.ToolHandler {
+	return func(ctx context.Context, req *mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+
+		// the arguments are the raw JSON of the request on the server side
+		args, err := json.Marshal(req.Params.Arguments)
+		if err != nil {
+			return ErrorResult(err), nil
+		}
+
+		facts := map[string]any{}
+		if err := json.Unmarshal(args, &facts); err != nil {
+			return ErrorResult(fmt.Errorf("%w: facts must be a JSON object: %v", storage.ErrInvalidInput, err)), nil
+		}
+		if facts == nil {
+			facts = map[string]any{}
+		}
+
+		out, err := h.grule.Evaluate(ctx, dto.EvaluateIn{RuleName: name, Facts: *dto.NewFact(facts)})
+		if err != nil {
+			return ErrorResult(err), nil
+		}
+
+		text, _ := json.Marshal(out)
+
+		result := &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{
+					Text: string(text),
+				},
+			},
+			StructuredContent: out,
+		}
+		return result, nil
+	}
+}
@@ -0,0 +1,50 @@
+package api
+
+import (
+	"context"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/hungpdn/mcp2grule/internal/api/handler"
+	"github.com/hungpdn/mcp2grule/internal/config"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// filterListings leaves out of tools/list and resources/list the tools and resources of the rulesets
+// the principal of the request may not read, so that the ruleset ACLs hide their names and descriptions.
+// It reads the principal from the context so it must be wrapped by middleware.Principal
+func (s *Server) filterListings(next mcp.MethodHandler) mcp.MethodHandler {
+	return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+
+		if !config.App.RBAC.Enabled || (method != "tools/list" && method != "resources/list") {
+			return next(ctx, method, req)
+		}
+
+		res, err := next(ctx, method, req)
+		if err != nil {
+			return res, err
+		}
+		readable, err := s.mcpHandler.Readable(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		switch result := res.(type) {
+		case *mcp.ListToolsResult:
+			s.rulesetTools.mu.Lock()
+			result.Tools = slices.DeleteFunc(result.Tools, func(tool *mcp.Tool) bool {
+				owner, ok := s.rulesetTools.owner[tool.Name]
+				return ok && !readable[owner]
+			})
+			s.rulesetTools.mu.Unlock()
+		case *mcp.ListResourcesResult:
+			result.Resources = slices.DeleteFunc(result.Resources, func(resource *mcp.Resource) bool {
+				name, err := url.PathUnescape(strings.TrimPrefix(resource.URI, handler.RulesetURIPrefix))
+				return strings.HasPrefix(resource.URI, handler.RulesetURIPrefix) && (err != nil || !readable[name])
+			})
+		}
+
+		return res, nil
+	}
+}
@@ -0,0 +1,37 @@
+package api
+
+import (
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// AddPrompts registers the prompts helping to author and understand GRL
+func (s *Server) AddPrompts() {
+
+	s.server.AddPrompt(&mcp.Prompt{
+		Name:        "write_rule",
+		Description: "Write the GRL of a ruleset from a natural-language policy and an example of the facts",
+		Arguments: []*mcp.PromptArgument{
+			{Name: "policy", Description: "The policy the rules implement, in plain language", Required: true},
+			{Name: "facts", Description: "An example of the facts as a JSON object"},
+			{Name: "ruleset", Description: "Name of an existing ruleset to extend instead of writing a new one"},
+		},
+	}, s.mcpHandler.WriteRule)
+
+	s.server.AddPrompt(&mcp.Prompt{
+		Name:        "explain_rule",
+		Description: "Explain a stored ruleset in plain language",
+		Arguments: []*mcp.PromptArgument{
+			{Name: "ruleset", Description: "Name of the ruleset", Required: true},
+		},
+	}, s.mcpHandler.ExplainRule)
+
+	s.server.AddPrompt(&mcp.Prompt{
+		Name:        "fix_rule",
+		Description: "Fix GRL that does not compile, given the compile error",
+		Arguments: []*mcp.PromptArgument{
+			{Name: "error", Description: "The compile error, as returned by grule.create, grule.update or grule.try", Required: true},
+			{Name: "grl", Description: "The GRL to fix, defaults to the GRL of the ruleset"},
+			{Name: "ruleset", Description: "Name of the stored ruleset to fix when no GRL is given"},
+		},
+	}, s.mcpHandler.FixRule)
+}
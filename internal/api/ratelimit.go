@@ -0,0 +1,91 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/hungpdn/mcp2grule/internal/api/handler"
+	"github.com/hungpdn/mcp2grule/internal/pkg/middleware"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// writeTools are the tools writing rulesets or pipelines, the other tools only read them
+var writeTools = map[string]bool{
+	"grule.create":          true,
+	"grule.update":          true,
+	"grule.delete":          true,
+	"grule.rollback":        true,
+	"grule.create_pipeline": true,
+	"grule.update_pipeline": true,
+	"grule.delete_pipeline": true,
+}
+
+// evaluateTools are the tools running rules
+var evaluateTools = map[string]bool{
+	"grule.evaluate":          true,
+	"grule.evaluate_batch":    true,
+	"grule.evaluate_set":      true,
+	"grule.evaluate_pipeline": true,
+	"grule.try":               true,
+}
+
+// rateCharge returns the rate limit charge of a JSON-RPC method, tool and arguments being the name
+// and arguments of the tool of a tools/call, false for the methods that are not limited. A batch
+// evaluation takes a token per fact set
+func rateCharge(method, tool string, arguments json.RawMessage) (middleware.Charge, bool) {
+
+	switch method {
+	case "tools/call":
+		switch {
+		case tool == "grule.evaluate_batch":
+			var in struct {
+				Facts []json.RawMessage `json:"facts"`
+			}
+			_ = json.Unmarshal(arguments, &in)
+			return middleware.Charge{Budget: middleware.BudgetEvaluate, Tokens: len(in.Facts)}, true
+		case evaluateTools[tool], strings.HasPrefix(tool, handler.RulesetToolPrefix):
+			return middleware.Charge{Budget: middleware.BudgetEvaluate, Tokens: 1}, true
+		case writeTools[tool]:
+			return middleware.Charge{Budget: middleware.BudgetWrite, Tokens: 1}, true
+		default:
+			return middleware.Charge{Budget: middleware.BudgetRead, Tokens: 1}, true
+		}
+	case "resources/read", "prompts/get":
+		return middleware.Charge{Budget: middleware.BudgetRead, Tokens: 1}, true
+	default:
+		return middleware.Charge{}, false
+	}
+}
+
+// sessionRateLimit limits the requests of each MCP session, for stdio which has no HTTP client
+// to limit. Tool calls over budget get a rate_limited tool error, other requests a protocol error
+func sessionRateLimit(l *middleware.RateLimiter) mcp.Middleware {
+	return func(next mcp.MethodHandler) mcp.MethodHandler {
+		return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+
+			var tool string
+			var arguments json.RawMessage
+			call, isCall := req.(*mcp.CallToolRequest)
+			if isCall {
+				tool = call.Params.Name
+				// the arguments are the raw JSON of the request on the server side
+				arguments, _ = json.Marshal(call.Params.Arguments)
+			}
+
+			charge, ok := rateCharge(method, tool, arguments)
+			if !ok {
+				return next(ctx, method, req)
+			}
+			if err := l.Allow(fmt.Sprintf("session:%p", req.GetSession()), charge); err != nil {
+				if isCall {
+					return handler.ErrorResult(err), nil
+				}
+				return nil, err
+			}
+
+			return next(ctx, method, req)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package api
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/hungpdn/mcp2grule/internal/api/handler"
+	"github.com/hungpdn/mcp2grule/internal/pkg/middleware"
+)
+
+func TestRateCharge(t *testing.T) {
+
+	tests := []struct {
+		method    string
+		tool      string
+		arguments string
+		want      middleware.Charge
+		limited   bool
+	}{
+		{"tools/call", "grule.evaluate", `{"rule_name":"loan"}`, middleware.Charge{Budget: middleware.BudgetEvaluate, Tokens: 1}, true},
+		{"tools/call", "grule.evaluate_set", `{"tag":"pricing"}`, middleware.Charge{Budget: middleware.BudgetEvaluate, Tokens: 1}, true},
+		{"tools/call", "grule.evaluate_pipeline", `{"pipeline":"loan"}`, middleware.Charge{Budget: middleware.BudgetEvaluate, Tokens: 1}, true},
+		{"tools/call", "grule.try", `{"grl":""}`, middleware.Charge{Budget: middleware.BudgetEvaluate, Tokens: 1}, true},
+		{"tools/call", handler.RulesetToolPrefix + "loan", `{}`, middleware.Charge{Budget: middleware.BudgetEvaluate, Tokens: 1}, true},
+		{"tools/call", "grule.evaluate_batch", `{"rule_name":"loan","facts":[{"M":{}},{"M":{}},{"M":{}}]}`, middleware.Charge{Budget: middleware.BudgetEvaluate, Tokens: 3}, true},
+		{"tools/call", "grule.evaluate_batch", `not json`, middleware.Charge{Budget: middleware.BudgetEvaluate, Tokens: 0}, true},
+		{"tools/call", "grule.update", `{}`, middleware.Charge{Budget: middleware.BudgetWrite, Tokens: 1}, true},
+		{"tools/call", "grule.delete_pipeline", `{}`, middleware.Charge{Budget: middleware.BudgetWrite, Tokens: 1}, true},
+		{"tools/call", "grule.list", `{}`, middleware.Charge{Budget: middleware.BudgetRead, Tokens: 1}, true},
+		{"resources/read", "", ``, middleware.Charge{Budget: middleware.BudgetRead, Tokens: 1}, true},
+		{"prompts/get", "", ``, middleware.Charge{Budget: middleware.BudgetRead, Tokens: 1}, true},
+		{"tools/list", "", ``, middleware.Charge{}, false},
+		{"initialize", "", ``, middleware.Charge{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.tool, func(t *testing.T) {
+			got, limited := rateCharge(tt.method, tt.tool, json.RawMessage(tt.arguments))
+			if got != tt.want || limited != tt.limited {
+				t.Fatalf("rateCharge() = %+v %v, want %+v %v", got, limited, tt.want, tt.limited)
+			}
+		})
+	}
+}
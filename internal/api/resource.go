@@ -0,0 +1,66 @@
+package api
+
+import (
+	"context"
+	"sync"
+
+	"github.com/hungpdn/mcp2grule/internal/api/handler"
+	"github.com/hungpdn/mcp2grule/internal/grule"
+	"github.com/hungpdn/mcp2grule/internal/pkg/logger"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// rulesetResources serializes the registration of the ruleset resources
+type rulesetResources struct {
+	mu sync.Mutex
+}
+
+// AddResources registers a resource for every stored ruleset and keeps the resources
+// in sync with the rulesets, subscribed clients are told through resources/updated
+func (s *Server) AddResources(ctx context.Context) error {
+
+	s.server.AddResourceTemplate(handler.RulesetTemplate(), s.mcpHandler.ReadRuleset)
+
+	s.rulesetResources.mu.Lock()
+	defer s.rulesetResources.mu.Unlock()
+
+	// subscribe before listing so that no change is missed, changes made meanwhile
+	// wait for the lock and are applied after the listing
+	s.mcpHandler.OnRulesetChange(func(change grule.Change) {
+		s.rulesetResources.mu.Lock()
+		uri := handler.RulesetURI(change.Name)
+		switch change.Kind {
+		case grule.RulesetCreated, grule.RulesetUpdated:
+			s.server.AddResource(handler.RulesetResource(*change.Ruleset), s.mcpHandler.ReadRuleset)
+		case grule.RulesetDeleted:
+			s.server.RemoveResources(uri)
+		}
+		s.rulesetResources.mu.Unlock()
+
+		// the subscribed sessions are notified one by one, the registrations need not wait for them
+		if change.Kind != grule.RulesetCreated {
+			s.resourceUpdated(uri)
+		}
+	})
+
+	rules, err := s.mcpHandler.Rulesets(ctx)
+	if err != nil {
+		return err
+	}
+	for _, rule := range rules {
+		s.server.AddResource(handler.RulesetResource(rule), s.mcpHandler.ReadRuleset)
+	}
+
+	logger.Infof("Registered %d ruleset resource(s)", len(rules))
+
+	return nil
+}
+
+// resourceUpdated notifies the clients subscribed to a resource that it changed
+func (s *Server) resourceUpdated(uri string) {
+
+	err := s.server.ResourceUpdated(context.Background(), &mcp.ResourceUpdatedNotificationParams{URI: uri})
+	if err != nil {
+		logger.Errorf("Failed to notify the update of resource %v: %v", uri, err)
+	}
+}
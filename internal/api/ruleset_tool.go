@@ -0,0 +1,87 @@
+package api
+
+import (
+	"context"
+	"sync"
+
+	"github.com/hungpdn/mcp2grule/internal/api/dto"
+	"github.com/hungpdn/mcp2grule/internal/api/handler"
+	"github.com/hungpdn/mcp2grule/internal/grule"
+	"github.com/hungpdn/mcp2grule/internal/pkg/logger"
+	"github.com/hungpdn/mcp2grule/internal/storage"
+)
+
+// rulesetTools tracks the dedicated tools registered for rulesets
+type rulesetTools struct {
+	mu    sync.Mutex
+	owner map[string]string // tool name to ruleset name, several names may sanitize alike
+}
+
+// AddRulesetTools registers a dedicated tool for every stored ruleset and keeps
+// the tools in sync with the rulesets, clients are told through tools/list_changed
+func (s *Server) AddRulesetTools(ctx context.Context) error {
+
+	s.rulesetTools.mu.Lock()
+	defer s.rulesetTools.mu.Unlock()
+
+	s.rulesetTools.owner = map[string]string{}
+
+	// subscribe before listing so that no change is missed, changes made meanwhile
+	// wait for the lock and are applied after the listing
+	s.mcpHandler.OnRulesetChange(func(change grule.Change) {
+		s.rulesetTools.mu.Lock()
+		defer s.rulesetTools.mu.Unlock()
+
+		if change.Kind == grule.RulesetDeleted {
+			s.removeRulesetTool(change.Name)
+			return
+		}
+		s.addRulesetTool(*change.Ruleset)
+	})
+
+	rules, err := s.mcpHandler.Rulesets(ctx)
+	if err != nil {
+		return err
+	}
+	for _, rule := range rules {
+		s.addRulesetTool(rule)
+	}
+
+	logger.Infof("Registered %d ruleset tool(s)", len(s.rulesetTools.owner))
+
+	return nil
+}
+
+// addRulesetTool registers or replaces the tool of a ruleset, callers must hold the lock
+func (s *Server) addRulesetTool(rule storage.Ruleset) {
+
+	name := handler.RulesetToolName(rule.Name)
+	if owner, ok := s.rulesetTools.owner[name]; ok && owner != rule.Name {
+		logger.Warnf("Skipping tool %v of rule %v, already registered for rule %v", name, rule.Name, owner)
+		return
+	}
+
+	tool, err := handler.RulesetTool(rule)
+	if err != nil {
+		logger.Errorf("Failed to describe tool %v of rule %v: %v", name, rule.Name, err)
+		return
+	}
+
+	tool.Annotations = readOnly()
+	tool.OutputSchema = outputSchema[dto.EvaluateOut]()
+
+	s.server.AddTool(tool, s.mcpHandler.EvaluateRuleset(rule.Name))
+	s.rulesetTools.owner[name] = rule.Name
+}
+
+// removeRulesetTool unregisters the tool of a ruleset, callers must hold the lock
+func (s *Server) removeRulesetTool(rule string) {
+
+	name := handler.RulesetToolName(rule)
+	if s.rulesetTools.owner[name] != rule {
+		return
+	}
+
+	s.server.RemoveTools(name)
+	delete(s.rulesetTools.owner, name)
+}
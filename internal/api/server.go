@@ -12,29 +12,62 @@ import (
 
 	"github.com/hungpdn/mcp2grule/internal/api/handler"
 	"github.com/hungpdn/mcp2grule/internal/config"
+	"github.com/hungpdn/mcp2grule/internal/pkg/auth"
 	"github.com/hungpdn/mcp2grule/internal/pkg/logger"
+	"github.com/hungpdn/mcp2grule/internal/pkg/metrics"
+	"github.com/hungpdn/mcp2grule/internal/pkg/middleware"
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 )
 
 // Server represents the MCP server with its handler and underlying mcp.Server instance.
 type Server struct {
-	mcpHandler *handler.MCPHandler
-	server     *mcp.Server
+	mcpHandler       *handler.MCPHandler
+	server           *mcp.Server
+	authenticator    auth.Authenticator      // Authenticates the HTTP transports, nil for stdio
+	limiter          *middleware.RateLimiter // Limits the requests of each client, nil for no limit
+	rulesetTools     rulesetTools
+	rulesetResources rulesetResources
 }
 
 // NewServer creates a new MCP server instance with the given application name, version, and handler.
-func NewServer(appName, verison string, mcpHandler *handler.MCPHandler) *Server {
-
-	mcpServer := mcp.NewServer(&mcp.Implementation{Name: appName, Version: verison}, nil)
+// The authenticator verifies the bearer token of the HTTP transports, the limiter limits the requests
+// of each HTTP client or stdio session.
+func NewServer(appName, verison string, mcpHandler *handler.MCPHandler, authenticator auth.Authenticator,
+	limiter *middleware.RateLimiter) *Server {
+
+	// the SDK tracks the subscriptions itself, the handlers only enable them
+	opts := &mcp.ServerOptions{
+		HasResources:       true,
+		SubscribeHandler:   func(context.Context, *mcp.SubscribeRequest) error { return nil },
+		UnsubscribeHandler: func(context.Context, *mcp.UnsubscribeRequest) error { return nil },
+	}
+	mcpServer := mcp.NewServer(&mcp.Implementation{Name: appName, Version: verison}, opts)
+	srv := &Server{mcpHandler: mcpHandler, server: mcpServer, authenticator: authenticator, limiter: limiter}
+
+	receiving := []mcp.Middleware{middleware.Principal, srv.filterListings, middleware.Logging, middleware.Metrics, toolErrors}
+	// the HTTP clients are limited before their requests reach the server, the stdio sessions inside
+	// it so that the calls over budget are logged and counted like the other failing calls
+	if limiter != nil && config.App.MCPTransport == config.MCPTransportStdio {
+		receiving = append(receiving, sessionRateLimit(limiter))
+	}
+	mcpServer.AddReceivingMiddleware(receiving...)
 
-	srv := &Server{mcpHandler: mcpHandler, server: mcpServer}
 	return srv
 }
 
 // Run starts the MCP server based on the configured transport method (stdio or HTTP).
 func (s *Server) Run(ctx context.Context) error {
-	// Register tools
+	// Register tools, prompts and resources
 	s.AddTools()
+	s.AddPrompts()
+	if err := s.AddResources(ctx); err != nil {
+		return err
+	}
+	if config.App.MCPRulesetTools {
+		if err := s.AddRulesetTools(ctx); err != nil {
+			return err
+		}
+	}
 
 	// Handle shutdown signals
 	ctx, cancel := context.WithCancel(ctx)
@@ -58,27 +91,37 @@ func (s *Server) Run(ctx context.Context) error {
 	// Start the server based on the configured transport
 	switch config.App.MCPTransport {
 	case config.MCPTransportStdio:
+		// stdio has no HTTP server to serve the metrics, they get their own
+		if config.App.Metrics.Enabled {
+			srv := &http.Server{
+				Addr:    config.App.Metrics.MetricsAddr(),
+				Handler: s.metricsHandler(http.NotFoundHandler()),
+			}
+			go func() {
+				if err := s.runHTTPServer(ctx, srv, "metrics"); err != nil {
+					logger.Errorf("Failed to serve metrics on %s: %v", srv.Addr, err)
+				}
+			}()
+		}
 		if err := s.runStdio(ctx); err != nil {
 			return err
 		}
 	case config.MCPTransportSSE:
 		httpHandler := mcp.NewSSEHandler(func(request *http.Request) *mcp.Server { return s.server })
-		// TODO: enable auth
-		// httpHandler = middleware.Auth(httpHandler, config.App.StreamableHTTPTransport.AuthToken)
 		srv := &http.Server{
 			Addr:    config.App.HTTPTransport.HttpAddr(),
-			Handler: httpHandler,
+			Handler: s.httpHandler(s.metricsHandler(middleware.SSESessions(httpHandler))),
 		}
-		if err := s.runHTTPServer(ctx, srv, config.App.MCPTransport); err != nil {
+		if err := s.runHTTPServer(ctx, srv, string(config.App.MCPTransport)); err != nil {
 			return err
 		}
 	case config.MCPTransportStreamableHTTP:
 		httpHandler := mcp.NewStreamableHTTPHandler(func(*http.Request) *mcp.Server { return s.server }, nil)
 		srv := &http.Server{
 			Addr:    config.App.HTTPTransport.HttpAddr(),
-			Handler: httpHandler,
+			Handler: s.httpHandler(s.metricsHandler(httpHandler)),
 		}
-		if err := s.runHTTPServer(ctx, srv, config.App.MCPTransport); err != nil {
+		if err := s.runHTTPServer(ctx, srv, string(config.App.MCPTransport)); err != nil {
 			return err
 		}
 	default:
@@ -88,8 +131,49 @@ func (s *Server) Run(ctx context.Context) error {
 	return nil
 }
 
+// httpHandler wraps the handler of an HTTP transport with the middlewares, the request ID
+// outermost so that the logs of the others carry it
+func (s *Server) httpHandler(next http.Handler) http.Handler {
+
+	// the limiter reads the principal the authentication puts in the request
+	if s.limiter != nil {
+		next = middleware.RateLimit(next, s.limiter, rateCharge)
+	}
+	if s.authenticator != nil {
+		next = middleware.Auth(next, s.authenticator)
+	}
+
+	return middleware.RequestID(next)
+}
+
+// metricsHandler serves the metrics on /metrics when enabled and the other paths with next,
+// the open sessions are counted when the metrics are scraped
+func (s *Server) metricsHandler(next http.Handler) http.Handler {
+
+	if !config.App.Metrics.Enabled {
+		return next
+	}
+
+	err := metrics.Sessions(string(config.App.MCPTransport), func() int {
+		count := 0
+		for range s.server.Sessions() {
+			count++
+		}
+		return count
+	})
+	if err != nil {
+		logger.Errorf("Failed to register the session metric: %v", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.Handler())
+	mux.Handle("/", next)
+
+	return mux
+}
+
 // runHTTPServer starts the MCP server using HTTP transport.
-func (s *Server) runHTTPServer(ctx context.Context, srv *http.Server, transport config.MCPTransport) error {
+func (s *Server) runHTTPServer(ctx context.Context, srv *http.Server, transport string) error {
 
 	serverErr := make(chan error, 1)
 	go func() {
@@ -1,39 +1,229 @@
 package api
 
 import (
+	"context"
+	"fmt"
+
+	"github.com/google/jsonschema-go/jsonschema"
+	"github.com/hungpdn/mcp2grule/internal/api/dto"
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 )
 
 func (s *Server) AddTools() {
 
 	mcp.AddTool(s.server, &mcp.Tool{
-		Name:        "grule.evaluate",
-		Description: "Evaluate facts against the rule set",
+		Name:         "grule.evaluate",
+		Title:        "Evaluate rule set",
+		Description:  "Evaluate facts against the rule set",
+		Annotations:  readOnly(),
+		OutputSchema: outputSchema[dto.EvaluateOut](),
 	}, s.mcpHandler.Evaluate)
 
 	mcp.AddTool(s.server, &mcp.Tool{
-		Name:        "grule.create",
-		Description: "Create a new rule",
+		Name:         "grule.evaluate_set",
+		Title:        "Evaluate several rule sets",
+		Description:  "Evaluate facts against several rule sets at once, by names or by tag, ordered by their salience",
+		Annotations:  readOnly(),
+		OutputSchema: outputSchema[dto.EvaluateSetOut](),
+	}, s.mcpHandler.EvaluateSet)
+
+	mcp.AddTool(s.server, &mcp.Tool{
+		Name:         "grule.evaluate_batch",
+		Title:        "Evaluate a batch of facts",
+		Description:  "Evaluate many fact sets against the rule set in one call, results keep the input order",
+		Annotations:  readOnly(),
+		OutputSchema: batchOutputSchema(),
+	}, s.mcpHandler.EvaluateBatch)
+
+	mcp.AddTool(s.server, &mcp.Tool{
+		Name:         "grule.create",
+		Title:        "Create rule set",
+		Description:  "Create a new rule",
+		Annotations:  additive(),
+		OutputSchema: outputSchema[dto.CreateOut](),
 	}, s.mcpHandler.Create)
 
 	mcp.AddTool(s.server, &mcp.Tool{
-		Name:        "grule.update",
-		Description: "Update an existing rule",
+		Name:         "grule.update",
+		Title:        "Update rule set",
+		Description:  "Update an existing rule",
+		Annotations:  destructive(false),
+		OutputSchema: outputSchema[dto.UpdateOut](),
 	}, s.mcpHandler.Update)
 
 	mcp.AddTool(s.server, &mcp.Tool{
-		Name:        "grule.delete",
-		Description: "Delete a rule by name",
+		Name:         "grule.delete",
+		Title:        "Delete rule set",
+		Description:  "Delete a rule by name",
+		Annotations:  destructive(true),
+		OutputSchema: outputSchema[dto.DeleteOut](),
 	}, s.mcpHandler.Delete)
 
 	mcp.AddTool(s.server, &mcp.Tool{
-		Name:        "grule.list",
-		Description: "List all existing rules",
+		Name:         "grule.list",
+		Title:        "List rule sets",
+		Description:  "List all existing rules",
+		Annotations:  readOnly(),
+		OutputSchema: outputSchema[dto.GetAllOut](),
 	}, s.mcpHandler.GetAll)
 
 	mcp.AddTool(s.server, &mcp.Tool{
-		Name:        "grule.detail",
-		Description: "Read an existing rule by name",
+		Name:         "grule.detail",
+		Title:        "Read rule set",
+		Description:  "Read an existing rule by name",
+		Annotations:  readOnly(),
+		OutputSchema: outputSchema[dto.GetByNameOut](),
 	}, s.mcpHandler.GetByName)
 
+	mcp.AddTool(s.server, &mcp.Tool{
+		Name:         "grule.schema",
+		Title:        "Read fact schema",
+		Description:  "Read the facts a rule expects, as its fact schema and the matching grule.evaluate input schema",
+		Annotations:  readOnly(),
+		OutputSchema: outputSchema[dto.SchemaOut](),
+	}, s.mcpHandler.Schema)
+
+	mcp.AddTool(s.server, &mcp.Tool{
+		Name:         "grule.history",
+		Title:        "List rule set revisions",
+		Description:  "List the revision history of a rule by name",
+		Annotations:  readOnly(),
+		OutputSchema: outputSchema[dto.HistoryOut](),
+	}, s.mcpHandler.History)
+
+	mcp.AddTool(s.server, &mcp.Tool{
+		Name:         "grule.rollback",
+		Title:        "Roll back rule set",
+		Description:  "Restore a previous revision of a rule",
+		Annotations:  destructive(false),
+		OutputSchema: outputSchema[dto.RollbackOut](),
+	}, s.mcpHandler.Rollback)
+
+	mcp.AddTool(s.server, &mcp.Tool{
+		Name:         "grule.try",
+		Title:        "Try GRL",
+		Description:  "Evaluate facts against inline GRL without saving it, returns the compile errors if any",
+		Annotations:  readOnly(),
+		OutputSchema: outputSchema[dto.TryOut](),
+	}, s.mcpHandler.Try)
+
+	mcp.AddTool(s.server, &mcp.Tool{
+		Name:         "grule.evaluate_pipeline",
+		Title:        "Evaluate pipeline",
+		Description:  "Evaluate facts against the rule sets of a pipeline, one stage after the other",
+		Annotations:  readOnly(),
+		OutputSchema: outputSchema[dto.EvaluatePipelineOut](),
+	}, s.mcpHandler.EvaluatePipeline)
+
+	mcp.AddTool(s.server, &mcp.Tool{
+		Name:         "grule.create_pipeline",
+		Title:        "Create pipeline",
+		Description:  "Create a new pipeline chaining rule sets",
+		Annotations:  additive(),
+		OutputSchema: outputSchema[dto.CreatePipelineOut](),
+	}, s.mcpHandler.CreatePipeline)
+
+	mcp.AddTool(s.server, &mcp.Tool{
+		Name:         "grule.update_pipeline",
+		Title:        "Update pipeline",
+		Description:  "Update an existing pipeline",
+		Annotations:  destructive(false),
+		OutputSchema: outputSchema[dto.UpdatePipelineOut](),
+	}, s.mcpHandler.UpdatePipeline)
+
+	mcp.AddTool(s.server, &mcp.Tool{
+		Name:         "grule.delete_pipeline",
+		Title:        "Delete pipeline",
+		Description:  "Delete a pipeline by name",
+		Annotations:  destructive(true),
+		OutputSchema: outputSchema[dto.DeletePipelineOut](),
+	}, s.mcpHandler.DeletePipeline)
+
+	mcp.AddTool(s.server, &mcp.Tool{
+		Name:         "grule.list_pipelines",
+		Title:        "List pipelines",
+		Description:  "List all existing pipelines",
+		Annotations:  readOnly(),
+		OutputSchema: outputSchema[dto.GetAllPipelinesOut](),
+	}, s.mcpHandler.GetAllPipelines)
+
+	mcp.AddTool(s.server, &mcp.Tool{
+		Name:         "grule.detail_pipeline",
+		Title:        "Read pipeline",
+		Description:  "Read an existing pipeline by name",
+		Annotations:  readOnly(),
+		OutputSchema: outputSchema[dto.GetPipelineOut](),
+	}, s.mcpHandler.GetPipeline)
+
+}
+
+// readOnly annotates a tool that does not change any rule set or pipeline,
+// hosts may call it without asking for confirmation
+func readOnly() *mcp.ToolAnnotations {
+	return &mcp.ToolAnnotations{
+		ReadOnlyHint:    true,
+		IdempotentHint:  true,
+		DestructiveHint: hint(false),
+		OpenWorldHint:   hint(false),
+	}
+}
+
+// additive annotates a tool that only adds rule sets or pipelines
+func additive() *mcp.ToolAnnotations {
+	return &mcp.ToolAnnotations{
+		DestructiveHint: hint(false),
+		OpenWorldHint:   hint(false),
+	}
+}
+
+// destructive annotates a tool that overwrites or deletes rule sets or pipelines,
+// hosts should ask for confirmation
+func destructive(idempotent bool) *mcp.ToolAnnotations {
+	return &mcp.ToolAnnotations{
+		IdempotentHint:  idempotent,
+		DestructiveHint: hint(true),
+		OpenWorldHint:   hint(false),
+	}
+}
+
+// hint returns an optional hint of the tool annotations
+func hint(b bool) *bool {
+	return &b
+}
+
+// outputSchema derives the output schema of a tool from its dto type
+func outputSchema[T any]() *jsonschema.Schema {
+
+	schema, err := jsonschema.For[T](nil)
+	if err != nil {
+		panic(fmt.Sprintf("output schema of %T: %v", *new(T), err))
+	}
+
+	return schema
+}
+
+// batchOutputSchema is the output schema of grule.evaluate_batch,
+// the modified facts of a failed fact set are null
+func batchOutputSchema() *jsonschema.Schema {
+
+	schema := outputSchema[dto.EvaluateBatchOut]()
+
+	facts := schema.Properties["results"].Items.Properties["modified_facts"]
+	facts.Type, facts.Types = "", []string{"null", "object"}
+
+	return schema
+}
+
+// toolErrors drops the structured content of tool error results, the SDK fills it
+// with the zero output of typed tools which would read as a valid but empty output
+func toolErrors(next mcp.MethodHandler) mcp.MethodHandler {
+	return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+
+		res, err := next(ctx, method, req)
+		if result, ok := res.(*mcp.CallToolResult); ok && result != nil && result.IsError {
+			result.StructuredContent = nil
+		}
+
+		return res, err
+	}
 }
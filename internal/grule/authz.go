@@ -0,0 +1,246 @@
+package grule
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/hungpdn/mcp2grule/internal/api/dto"
+	"github.com/hungpdn/mcp2grule/internal/pkg/auth"
+	"github.com/hungpdn/mcp2grule/internal/pkg/authz"
+	"github.com/hungpdn/mcp2grule/internal/pkg/logger"
+	"github.com/hungpdn/mcp2grule/internal/storage"
+)
+
+// authorized checks the role and the ruleset ACLs of the principal of each call
+// before handing it to the service
+type authorized struct {
+	IGrule
+	policy    *authz.Policy
+	anonymous *authz.Subject // Subject of the calls without principal, nil to deny them
+}
+
+// WithAuthorization wraps a service so that every call is authorized by the policy for the principal
+// of its context. Calls without principal, made over stdio, get the anonymous role, RoleNone denies them
+func WithAuthorization(service IGrule, policy *authz.Policy, anonymous authz.Role) IGrule {
+
+	a := &authorized{IGrule: service, policy: policy}
+	if anonymous != authz.RoleNone {
+		a.anonymous = &authz.Subject{Name: "anonymous", Role: anonymous}
+	}
+
+	return a
+}
+
+// subject returns the subject of a call
+func (a *authorized) subject(ctx context.Context) (authz.Subject, error) {
+
+	if authz.IsServer(ctx) {
+		return authz.Subject{Name: "server", Role: authz.RoleAdmin}, nil
+	}
+	if p := auth.FromContext(ctx); p != nil {
+		return a.policy.Subject(p), nil
+	}
+	if a.anonymous != nil {
+		return *a.anonymous, nil
+	}
+
+	return authz.Subject{}, fmt.Errorf("%w: the call is not authenticated", authz.ErrForbidden)
+}
+
+// authorize checks that the principal of a call may run an operation on every ruleset,
+// or on no ruleset in particular when none is given
+func (a *authorized) authorize(ctx context.Context, op authz.Operation, rulesets ...string) error {
+
+	s, err := a.subject(ctx)
+	if err == nil {
+		err = a.policy.Authorize(s, op, "")
+	}
+	for _, name := range rulesets {
+		if err != nil {
+			break
+		}
+		err = a.policy.Authorize(s, op, name)
+	}
+
+	if err != nil {
+		logger.WithContext(ctx).Warnf("Denied %s of %v: %v", op, rulesets, err)
+	}
+
+	return err
+}
+
+func (a *authorized) Evaluate(ctx context.Context, in dto.EvaluateIn) (*dto.EvaluateOut, error) {
+	if err := a.authorize(ctx, authz.OpEvaluate, in.RuleName); err != nil {
+		return nil, err
+	}
+	return a.IGrule.Evaluate(ctx, in)
+}
+
+// EvaluateSet authorizes every ruleset carrying the tag when the set is selected by tag
+func (a *authorized) EvaluateSet(ctx context.Context, in dto.EvaluateSetIn) (*dto.EvaluateSetOut, error) {
+
+	names := in.RuleNames
+	if in.Tag != "" {
+		if err := a.authorize(ctx, authz.OpEvaluate); err != nil {
+			return nil, err
+		}
+		all, err := a.IGrule.GetAll(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, rule := range all.Rulesets {
+			if slices.Contains(rule.Tags, in.Tag) {
+				names = append(names, rule.Name)
+			}
+		}
+	}
+
+	if err := a.authorize(ctx, authz.OpEvaluate, names...); err != nil {
+		return nil, err
+	}
+
+	return a.IGrule.EvaluateSet(ctx, in)
+}
+
+func (a *authorized) EvaluateBatch(ctx context.Context, in dto.EvaluateBatchIn, progress Progress) (*dto.EvaluateBatchOut, error) {
+	if err := a.authorize(ctx, authz.OpEvaluate, in.RuleName); err != nil {
+		return nil, err
+	}
+	return a.IGrule.EvaluateBatch(ctx, in, progress)
+}
+
+func (a *authorized) Create(ctx context.Context, in dto.CreateIn) (*dto.CreateOut, error) {
+	if err := a.authorize(ctx, authz.OpWrite, in.Name); err != nil {
+		return nil, err
+	}
+	return a.IGrule.Create(ctx, in)
+}
+
+func (a *authorized) Update(ctx context.Context, name string, in dto.UpdateIn) (*dto.UpdateOut, error) {
+	if err := a.authorize(ctx, authz.OpWrite, name); err != nil {
+		return nil, err
+	}
+	return a.IGrule.Update(ctx, name, in)
+}
+
+func (a *authorized) Delete(ctx context.Context, name string) (*dto.DeleteOut, error) {
+	if err := a.authorize(ctx, authz.OpDelete, name); err != nil {
+		return nil, err
+	}
+	return a.IGrule.Delete(ctx, name)
+}
+
+// GetAll leaves out the rulesets the principal may not read
+func (a *authorized) GetAll(ctx context.Context) (*dto.GetAllOut, error) {
+
+	if err := a.authorize(ctx, authz.OpRead); err != nil {
+		return nil, err
+	}
+	s, err := a.subject(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := a.IGrule.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	out.Rulesets = slices.DeleteFunc(out.Rulesets, func(rule storage.Ruleset) bool {
+		return a.policy.Authorize(s, authz.OpRead, rule.Name) != nil
+	})
+
+	return out, nil
+}
+
+func (a *authorized) GetByName(ctx context.Context, name string) (*dto.GetByNameOut, error) {
+	if err := a.authorize(ctx, authz.OpRead, name); err != nil {
+		return nil, err
+	}
+	return a.IGrule.GetByName(ctx, name)
+}
+
+func (a *authorized) Schema(ctx context.Context, name string) (*dto.SchemaOut, error) {
+	if err := a.authorize(ctx, authz.OpRead, name); err != nil {
+		return nil, err
+	}
+	return a.IGrule.Schema(ctx, name)
+}
+
+func (a *authorized) History(ctx context.Context, name string) (*dto.HistoryOut, error) {
+	if err := a.authorize(ctx, authz.OpRead, name); err != nil {
+		return nil, err
+	}
+	return a.IGrule.History(ctx, name)
+}
+
+func (a *authorized) Rollback(ctx context.Context, name string, in dto.RollbackIn) (*dto.RollbackOut, error) {
+	if err := a.authorize(ctx, authz.OpWrite, name); err != nil {
+		return nil, err
+	}
+	return a.IGrule.Rollback(ctx, name, in)
+}
+
+func (a *authorized) Try(ctx context.Context, in dto.TryIn) (*dto.TryOut, error) {
+	if err := a.authorize(ctx, authz.OpEvaluate); err != nil {
+		return nil, err
+	}
+	return a.IGrule.Try(ctx, in)
+}
+
+// EvaluatePipeline authorizes the ruleset of every stage of the pipeline
+func (a *authorized) EvaluatePipeline(ctx context.Context, in dto.EvaluatePipelineIn) (*dto.EvaluatePipelineOut, error) {
+
+	if err := a.authorize(ctx, authz.OpEvaluate); err != nil {
+		return nil, err
+	}
+	pipeline, err := a.IGrule.GetPipeline(ctx, in.Pipeline)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(pipeline.Pipeline.Stages))
+	for _, stage := range pipeline.Pipeline.Stages {
+		names = append(names, stage.Ruleset)
+	}
+	if err := a.authorize(ctx, authz.OpEvaluate, names...); err != nil {
+		return nil, err
+	}
+
+	return a.IGrule.EvaluatePipeline(ctx, in)
+}
+
+func (a *authorized) CreatePipeline(ctx context.Context, in dto.CreatePipelineIn) (*dto.CreatePipelineOut, error) {
+	if err := a.authorize(ctx, authz.OpWrite); err != nil {
+		return nil, err
+	}
+	return a.IGrule.CreatePipeline(ctx, in)
+}
+
+func (a *authorized) UpdatePipeline(ctx context.Context, name string, in dto.UpdatePipelineIn) (*dto.UpdatePipelineOut, error) {
+	if err := a.authorize(ctx, authz.OpWrite); err != nil {
+		return nil, err
+	}
+	return a.IGrule.UpdatePipeline(ctx, name, in)
+}
+
+func (a *authorized) DeletePipeline(ctx context.Context, name string) (*dto.DeletePipelineOut, error) {
+	if err := a.authorize(ctx, authz.OpDelete); err != nil {
+		return nil, err
+	}
+	return a.IGrule.DeletePipeline(ctx, name)
+}
+
+func (a *authorized) GetAllPipelines(ctx context.Context) (*dto.GetAllPipelinesOut, error) {
+	if err := a.authorize(ctx, authz.OpRead); err != nil {
+		return nil, err
+	}
+	return a.IGrule.GetAllPipelines(ctx)
+}
+
+func (a *authorized) GetPipeline(ctx context.Context, name string) (*dto.GetPipelineOut, error) {
+	if err := a.authorize(ctx, authz.OpRead); err != nil {
+		return nil, err
+	}
+	return a.IGrule.GetPipeline(ctx, name)
+}
@@ -0,0 +1,111 @@
+package grule
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/hungpdn/mcp2grule/internal/api/dto"
+	"github.com/hungpdn/mcp2grule/internal/pkg/metrics"
+	"github.com/hungpdn/mcp2grule/internal/storage"
+)
+
+// EvaluateBatch evaluates many fact sets against the same ruleset with a bounded pool of workers,
+// a failing fact set is reported in its result and does not stop the others
+func (g *grule) EvaluateBatch(ctx context.Context, in dto.EvaluateBatchIn, progress Progress) (*dto.EvaluateBatchOut, error) {
+
+	total := len(in.Facts)
+	if g.cfg.BatchMaxSize > 0 && total > g.cfg.BatchMaxSize {
+		return nil, fmt.Errorf("%w: batch of %d fact sets exceeds the limit of %d",
+			storage.ErrInvalidInput, total, g.cfg.BatchMaxSize)
+	}
+
+	rule, err := g.lookup(ctx, in.RuleName)
+	if err != nil {
+		return nil, err
+	}
+
+	workers := min(max(g.cfg.BatchWorkers, 1), max(total, 1))
+	results := make([]dto.BatchResult, total)
+	jobs := make(chan int)
+
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex // serializes progress so that it is reported in increasing order
+		done int
+	)
+
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = g.evaluateOne(ctx, rule, i, &in.Facts[i])
+
+				if progress != nil {
+					mu.Lock()
+					done++
+					progress(done, total)
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+
+	for i := range in.Facts {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	out := &dto.EvaluateBatchOut{Results: results}
+	for _, r := range results {
+		if r.Error != "" {
+			out.Failed++
+		}
+	}
+
+	return out, nil
+}
+
+// evaluateOne evaluates a single fact set of a batch
+func (g *grule) evaluateOne(ctx context.Context, rule *storage.Ruleset, index int, facts *dto.Fact) dto.BatchResult {
+
+	if err := ctx.Err(); err != nil {
+		return dto.BatchResult{Index: index, Error: err.Error()}
+	}
+	if facts.M == nil {
+		facts.M = map[string]any{}
+	}
+
+	if err := g.checkFacts(rule, facts.M); err != nil {
+		return dto.BatchResult{Index: index, Error: err.Error()}
+	}
+
+	if err := g.reload(ctx, rule.Name); err != nil {
+		return dto.BatchResult{Index: index, Error: err.Error()}
+	}
+
+	defer observeEvaluate(rule.Name, time.Now())
+
+	if err := g.engine.Execute(ctx, rule.Name, facts); err != nil {
+		return dto.BatchResult{Index: index, Error: executionError(err).Error()}
+	}
+
+	return dto.BatchResult{Index: index, ModifiedFacts: facts.AsMap()}
+}
+
+// reload makes sure the ruleset of a batch is still compiled in the engine. When the cache evicted it
+// since the batch started, it is read again and built under the read lock like lookup does, so that
+// a ruleset deleted or updated meanwhile is not built back from the state the batch started with
+func (g *grule) reload(ctx context.Context, name string) error {
+
+	if g.engine.ContainsRule(name) {
+		metrics.CacheHits.Inc()
+		return nil
+	}
+	_, err := g.lookup(ctx, name)
+
+	return err
+}
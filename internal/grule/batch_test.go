@@ -0,0 +1,164 @@
+package grule
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/hungpdn/mcp2grule/internal/api/dto"
+	"github.com/hungpdn/mcp2grule/internal/config"
+	"github.com/hungpdn/mcp2grule/internal/storage"
+)
+
+// echo copies the fact i into the fact echo
+const echo = `rule Echo "" salience 1 { when true then Fact.Set("echo", Fact.Get("i")); Retract("Echo"); }`
+
+// batchFacts returns n fact sets numbered from 0
+func batchFacts(n int) []dto.Fact {
+
+	facts := make([]dto.Fact, n)
+	for i := range facts {
+		facts[i] = *dto.NewFact(map[string]any{"i": i})
+	}
+
+	return facts
+}
+
+func TestEvaluateBatchOrder(t *testing.T) {
+
+	ctx := context.Background()
+	store := storage.NewMemory()
+	g := New(config.Grule{BatchWorkers: 8}, store, store)
+	if _, err := g.Create(ctx, dto.CreateIn{Name: "echo", GRL: echo}); err != nil {
+		t.Fatal(err)
+	}
+
+	const total = 500
+	var reported []int
+	out, err := g.EvaluateBatch(ctx, dto.EvaluateBatchIn{RuleName: "echo", Facts: batchFacts(total)}, func(done, n int) {
+		if n != total {
+			t.Errorf("progress total %d, want %d", n, total)
+		}
+		reported = append(reported, done)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if out.Failed != 0 || len(out.Results) != total {
+		t.Fatalf("%d results, %d failed, want %d results and none failed", len(out.Results), out.Failed, total)
+	}
+	for i, r := range out.Results {
+		if r.Index != i || r.ModifiedFacts["echo"] != i {
+			t.Fatalf("result %d has index %d and echo %v, want both %d", i, r.Index, r.ModifiedFacts["echo"], i)
+		}
+	}
+	for i, done := range reported {
+		if done != i+1 {
+			t.Fatalf("progress reported %v, want 1 to %d in order", reported, total)
+		}
+	}
+	if len(reported) != total {
+		t.Fatalf("progress reported %d times, want %d", len(reported), total)
+	}
+}
+
+func TestEvaluateBatchErrors(t *testing.T) {
+
+	ctx := context.Background()
+	schema := map[string]any{
+		"type":       "object",
+		"properties": map[string]any{"i": map[string]any{"type": "integer"}},
+		"required":   []any{"i"},
+	}
+
+	tests := []struct {
+		name    string
+		max     int
+		facts   []dto.Fact
+		during  func(g IGrule) // called after the first fact set is evaluated
+		err     error          // error of the whole batch
+		failed  []int          // indexes of the fact sets whose evaluation fails
+		message string         // part of the error of the failed fact sets
+	}{
+		{
+			name:  "within the limit",
+			max:   3,
+			facts: batchFacts(3),
+		},
+		{
+			name:  "over the limit",
+			max:   3,
+			facts: batchFacts(4),
+			err:   storage.ErrInvalidInput,
+		},
+		{
+			name: "facts not matching the schema",
+			facts: []dto.Fact{
+				*dto.NewFact(map[string]any{"i": 0}),
+				*dto.NewFact(map[string]any{"i": "one"}),
+				*dto.NewFact(map[string]any{}),
+				*dto.NewFact(map[string]any{"i": 3}),
+			},
+			failed:  []int{1, 2},
+			message: "facts do not match",
+		},
+		{
+			name:  "ruleset deleted during the batch",
+			facts: batchFacts(3),
+			during: func(g IGrule) {
+				if _, err := g.Delete(ctx, "echo"); err != nil {
+					t.Error(err)
+				}
+			},
+			failed:  []int{1, 2},
+			message: storage.ErrNotFound.Error(),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := storage.NewMemory()
+			g := New(config.Grule{BatchWorkers: 1, BatchMaxSize: tt.max}, store, store)
+			if _, err := g.Create(ctx, dto.CreateIn{Name: "echo", GRL: echo, FactSchema: schema}); err != nil {
+				t.Fatal(err)
+			}
+
+			out, err := g.EvaluateBatch(ctx, dto.EvaluateBatchIn{RuleName: "echo", Facts: tt.facts}, func(done, _ int) {
+				if done == 1 && tt.during != nil {
+					tt.during(g)
+				}
+			})
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("EvaluateBatch() = %v, want %v", err, tt.err)
+			}
+			if err != nil {
+				return
+			}
+
+			var failed []int
+			for i, r := range out.Results {
+				if r.Error == "" {
+					continue
+				}
+				failed = append(failed, i)
+				if !strings.Contains(r.Error, tt.message) {
+					t.Errorf("result %d error %q, want it to contain %q", i, r.Error, tt.message)
+				}
+			}
+			if out.Failed != len(tt.failed) || !slices.Equal(failed, tt.failed) {
+				t.Fatalf("failed fact sets %v (%d), want %v", failed, out.Failed, tt.failed)
+			}
+		})
+	}
+
+	t.Run("missing ruleset", func(t *testing.T) {
+		store := storage.NewMemory()
+		g := New(config.Grule{}, store, store)
+		_, err := g.EvaluateBatch(ctx, dto.EvaluateBatchIn{RuleName: "nope", Facts: batchFacts(2)}, nil)
+		if !errors.Is(err, storage.ErrNotFound) {
+			t.Fatalf("EvaluateBatch() = %v, want %v", err, storage.ErrNotFound)
+		}
+	})
+}
@@ -0,0 +1,86 @@
+package grule
+
+import (
+	"context"
+
+	"github.com/hungpdn/mcp2grule/internal/pkg/logger"
+	"github.com/hungpdn/mcp2grule/internal/storage"
+)
+
+// ChangeKind is the kind of a change made to a ruleset
+type ChangeKind string
+
+const (
+	RulesetCreated ChangeKind = "created"
+	RulesetUpdated ChangeKind = "updated"
+	RulesetDeleted ChangeKind = "deleted"
+)
+
+// Change describes a ruleset written through the service
+type Change struct {
+	Kind    ChangeKind
+	Name    string
+	Ruleset *storage.Ruleset // State after the change, nil when deleted
+}
+
+// Subscribe registers fn to be called after every ruleset change. Changes are delivered
+// in the order they were made, one at a time, after the write that made them returned
+func (g *grule) Subscribe(fn func(Change)) {
+	g.subsMu.Lock()
+	defer g.subsMu.Unlock()
+
+	g.subs = append(g.subs, fn)
+}
+
+// notify queues a change for the subscribers with the stored state of the ruleset,
+// callers must hold the write lock so that changes are queued in the order they were made
+func (g *grule) notify(ctx context.Context, kind ChangeKind, name string) {
+	g.subsMu.Lock()
+	subscribed := len(g.subs) > 0
+	g.subsMu.Unlock()
+
+	if !subscribed {
+		return
+	}
+
+	change := Change{Kind: kind, Name: name}
+	if kind != RulesetDeleted {
+		rule, err := g.store.GetByName(ctx, name)
+		if err != nil {
+			logger.WithContext(ctx).Errorf("Failed to read rule %v to notify its change: %v", name, err)
+			return
+		}
+		change.Ruleset = rule
+	}
+
+	g.subsMu.Lock()
+	defer g.subsMu.Unlock()
+
+	// subscribers notify the MCP sessions, a slow one must not hold the writes and evaluations
+	g.pending = append(g.pending, change)
+	if !g.delivering {
+		g.delivering = true
+		go g.deliver()
+	}
+}
+
+// deliver calls the subscribers with the queued changes until none is left
+func (g *grule) deliver() {
+	for {
+		g.subsMu.Lock()
+		changes, subs := g.pending, g.subs
+		g.pending = nil
+		if len(changes) == 0 {
+			g.delivering = false
+			g.subsMu.Unlock()
+			return
+		}
+		g.subsMu.Unlock()
+
+		for _, change := range changes {
+			for _, fn := range subs {
+				fn(change)
+			}
+		}
+	}
+}
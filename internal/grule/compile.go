@@ -0,0 +1,96 @@
+package grule
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/hungpdn/grule-plus/engine"
+	"github.com/hungpdn/mcp2grule/internal/api/dto"
+	"github.com/hyperjumptech/grule-rule-engine/ast"
+	"github.com/hyperjumptech/grule-rule-engine/builder"
+	"github.com/hyperjumptech/grule-rule-engine/pkg"
+)
+
+// syntaxError matches the errors reported by the grule parser, e.g. "grl error on 3:14 mismatched input",
+// where the line counts from 1 and the column from 0
+var syntaxError = regexp.MustCompile(`^grl error on (\d+):(\d+) (.*)$`)
+
+// CompileError is returned when a GRL script does not compile
+type CompileError struct {
+	Errors []dto.CompileIssue
+}
+
+// Error implements the error interface
+func (e *CompileError) Error() string {
+	issues := make([]string, 0, len(e.Errors))
+	for _, issue := range e.Errors {
+		if issue.Line == 0 {
+			issues = append(issues, issue.Message)
+			continue
+		}
+		issues = append(issues, fmt.Sprintf("%d:%d %s", issue.Line, issue.Column, issue.Message))
+	}
+	return "invalid GRL: " + strings.Join(issues, "; ")
+}
+
+// compile builds a GRL script into a new knowledge library
+func compile(grl string) (*ast.KnowledgeLibrary, error) {
+
+	library := ast.NewKnowledgeLibrary()
+	if err := build(library, grl); err != nil {
+		return nil, err
+	}
+
+	kb := library.GetKnowledgeBase(engine.LibraryName, engine.LibraryVersion)
+	if kb == nil || len(kb.RuleEntries) == 0 {
+		return nil, &CompileError{Errors: []dto.CompileIssue{{Message: "GRL does not declare any rule"}}}
+	}
+
+	return library, nil
+}
+
+// build adds the rules of a GRL script to a knowledge library. The grule builder panics on some
+// scripts it parses, e.g. a salience out of the int32 range, the panic is reported as a CompileError
+func build(library *ast.KnowledgeLibrary, grl string) (err error) {
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = &CompileError{Errors: []dto.CompileIssue{{Message: fmt.Sprint(r)}}}
+		}
+	}()
+
+	rb := builder.NewRuleBuilder(library)
+	err = rb.BuildRuleFromResource(engine.LibraryName, engine.LibraryVersion, pkg.NewBytesResource([]byte(grl)))
+	if err != nil {
+		return newCompileError(err)
+	}
+
+	return nil
+}
+
+// newCompileError converts a grule builder error into a CompileError
+func newCompileError(err error) *CompileError {
+
+	var reporter *pkg.GruleErrorReporter
+	if !errors.As(err, &reporter) {
+		return &CompileError{Errors: []dto.CompileIssue{{Message: err.Error()}}}
+	}
+
+	issues := make([]dto.CompileIssue, 0, len(reporter.Errors))
+	for _, e := range reporter.Errors {
+		match := syntaxError.FindStringSubmatch(e.Error())
+		if match == nil {
+			issues = append(issues, dto.CompileIssue{Message: e.Error()})
+			continue
+		}
+
+		line, _ := strconv.Atoi(match[1])
+		column, _ := strconv.Atoi(match[2])
+		issues = append(issues, dto.CompileIssue{Line: line, Column: column + 1, Message: match[3]})
+	}
+
+	return &CompileError{Errors: issues}
+}
@@ -0,0 +1,145 @@
+package grule
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/hungpdn/mcp2grule/internal/api/dto"
+	"github.com/hungpdn/mcp2grule/internal/config"
+	"github.com/hungpdn/mcp2grule/internal/storage"
+)
+
+// outOfRange is a rule whose salience makes the grule builder panic
+const outOfRange = `rule A "" salience 9999999999 { when true then Retract("A"); }`
+
+func TestTryReportsBuilderPanics(t *testing.T) {
+
+	g := New(config.Grule{}, storage.NewMemory(), storage.NewMemory())
+
+	tests := []struct {
+		name string
+		grl  string
+		want string // part of the compile error, empty when the GRL compiles
+	}{
+		{"salience out of range", outOfRange, "out of range"},
+		{"negative salience out of range", `rule A "" salience -9999999999 { when true then Retract("A"); }`, "out of range"},
+		{"largest salience", `rule A "" salience 2147483647 { when true then Retract("A"); }`, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := g.Try(context.Background(), dto.TryIn{GRL: tt.grl, Facts: *dto.NewFact(nil)})
+			if err != nil {
+				t.Fatalf("Try() = %v, want the compile errors in the output", err)
+			}
+			if tt.want == "" {
+				if len(out.Errors) != 0 {
+					t.Fatalf("compile errors %v, want none", out.Errors)
+				}
+				return
+			}
+			if len(out.Errors) != 1 || !strings.Contains(out.Errors[0].Message, tt.want) {
+				t.Fatalf("compile errors %v, want one containing %q", out.Errors, tt.want)
+			}
+		})
+	}
+}
+
+func TestWritesRejectBuilderPanics(t *testing.T) {
+
+	ctx := context.Background()
+	store := storage.NewMemory()
+	g := New(config.Grule{}, store, store)
+
+	const valid = `rule A "" salience 10 { when true then Retract("A"); }`
+	if _, err := g.Create(ctx, dto.CreateIn{Name: "loan", GRL: valid}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		write func() error
+	}{
+		{"create", func() error {
+			_, err := g.Create(ctx, dto.CreateIn{Name: "other", GRL: outOfRange})
+			return err
+		}},
+		{"update", func() error {
+			_, err := g.Update(ctx, "loan", dto.UpdateIn{Name: "loan", GRL: outOfRange})
+			return err
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var compileErr *CompileError
+			if err := tt.write(); !errors.As(err, &compileErr) {
+				t.Fatalf("%s = %v, want a CompileError", tt.name, err)
+			}
+
+			rules, err := store.GetAll(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(rules) != 1 || rules[0].GRL != valid {
+				t.Fatalf("stored rulesets %v, want loan unchanged", rules)
+			}
+		})
+	}
+}
+
+func TestCompileErrorPosition(t *testing.T) {
+
+	tests := []struct {
+		name string
+		grl  string
+		want []dto.CompileIssue // line and column of each error, counted from 1, and part of its message
+	}{
+		{
+			name: "missing semicolon",
+			grl:  "rule A \"\" salience 1 {\n\twhen true\n\tthen Retract(\"A\")\n}",
+			want: []dto.CompileIssue{{Line: 4, Column: 1, Message: "missing ';' at '}'"}},
+		},
+		{
+			name: "missing argument",
+			grl:  "rule A \"\" {\n\twhen\n\t\ttrue\n\tthen\n\t\tFact.Set(\"a\", );\n}",
+			want: []dto.CompileIssue{{Line: 5, Column: 17, Message: "mismatched input ')'"}},
+		},
+		{
+			name: "invalid rule name",
+			grl:  `rule 1A "" { when true then Retract("A"); }`,
+			want: []dto.CompileIssue{{Line: 1, Column: 6, Message: "extraneous input '1'"}},
+		},
+		{
+			name: "several errors",
+			grl:  "rule A \"\" salience 1 {\n\twhen true &&\n\tthen Retract(\"A\");\n}",
+			want: []dto.CompileIssue{
+				{Line: 3, Column: 2, Message: "extraneous input 'then'"},
+				{Line: 3, Column: 19, Message: "mismatched input ';' expecting THEN"},
+			},
+		},
+		{
+			name: "no rule",
+			grl:  "",
+			want: []dto.CompileIssue{{Message: "GRL does not declare any rule"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := compile(tt.grl)
+			var compileErr *CompileError
+			if !errors.As(err, &compileErr) {
+				t.Fatalf("compile() = %v, want a CompileError", err)
+			}
+			if len(compileErr.Errors) != len(tt.want) {
+				t.Fatalf("compile errors %+v, want %+v", compileErr.Errors, tt.want)
+			}
+			for i, want := range tt.want {
+				got := compileErr.Errors[i]
+				if got.Line != want.Line || got.Column != want.Column || !strings.HasPrefix(got.Message, want.Message) {
+					t.Fatalf("compile error %+v, want %+v", got, want)
+				}
+			}
+		})
+	}
+}
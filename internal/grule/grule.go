@@ -2,60 +2,149 @@ package grule
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
 
 	"github.com/hungpdn/grule-plus/engine"
 	"github.com/hungpdn/mcp2grule/internal/api/dto"
 	"github.com/hungpdn/mcp2grule/internal/config"
 	"github.com/hungpdn/mcp2grule/internal/pkg/logger"
+	"github.com/hungpdn/mcp2grule/internal/pkg/metrics"
 	"github.com/hungpdn/mcp2grule/internal/storage"
 )
 
+// factName is the name under which facts are exposed to GRL scripts
+const factName = "Fact"
+
+// ErrExecution is returned when the engine fails while executing rules, e.g. when a rule keeps firing
+var ErrExecution = errors.New("rule execution failed")
+
 // IGrule is the interface for Grule service
 type IGrule interface {
+	Warmup(ctx context.Context) error
+	Subscribe(fn func(Change))
 	Evaluate(ctx context.Context, in dto.EvaluateIn) (*dto.EvaluateOut, error)
+	EvaluateSet(ctx context.Context, in dto.EvaluateSetIn) (*dto.EvaluateSetOut, error)
+	EvaluateBatch(ctx context.Context, in dto.EvaluateBatchIn, progress Progress) (*dto.EvaluateBatchOut, error)
 	Create(ctx context.Context, in dto.CreateIn) (*dto.CreateOut, error)
 	Update(ctx context.Context, name string, in dto.UpdateIn) (*dto.UpdateOut, error)
 	Delete(ctx context.Context, name string) (*dto.DeleteOut, error)
 	GetAll(ctx context.Context) (*dto.GetAllOut, error)
 	GetByName(ctx context.Context, name string) (*dto.GetByNameOut, error)
+	Schema(ctx context.Context, name string) (*dto.SchemaOut, error)
+	History(ctx context.Context, name string) (*dto.HistoryOut, error)
+	Rollback(ctx context.Context, name string, in dto.RollbackIn) (*dto.RollbackOut, error)
+	Try(ctx context.Context, in dto.TryIn) (*dto.TryOut, error)
+	EvaluatePipeline(ctx context.Context, in dto.EvaluatePipelineIn) (*dto.EvaluatePipelineOut, error)
+	CreatePipeline(ctx context.Context, in dto.CreatePipelineIn) (*dto.CreatePipelineOut, error)
+	UpdatePipeline(ctx context.Context, name string, in dto.UpdatePipelineIn) (*dto.UpdatePipelineOut, error)
+	DeletePipeline(ctx context.Context, name string) (*dto.DeletePipelineOut, error)
+	GetAllPipelines(ctx context.Context) (*dto.GetAllPipelinesOut, error)
+	GetPipeline(ctx context.Context, name string) (*dto.GetPipelineOut, error)
+}
+
+// ruleEngine is the grule-plus engine used by the service,
+// engine.IGruleEngine does not expose the removal of a rule
+type ruleEngine interface {
+	engine.IGruleEngine
+	RemoveRule(rule string)
 }
 
+// Progress is notified each time a unit of a long running operation completes
+type Progress func(done, total int)
+
 // grule is the implementation of IGrule
 type grule struct {
-	cfg    config.Grule
-	store  storage.IRulesetStorage
-	engine engine.IGruleEngine
+	cfg        config.Grule
+	store      storage.IRulesetStorage
+	pipelines  storage.IPipelineStorage
+	engine     ruleEngine
+	mu         sync.RWMutex // keeps storage and engine in sync across writes
+	schemas    sync.Map     // ruleset name to its resolvedSchema
+	built      sync.Map     // names of the rulesets built into the engine, to tell evictions from first builds
+	subs       []func(Change)
+	pending    []Change   // changes not yet delivered to subs
+	delivering bool       // whether a goroutine is delivering the pending changes
+	subsMu     sync.Mutex // protects subs, pending and delivering
 }
 
 // New creates a new Grule service
-func New(cfg config.Grule, store storage.IRulesetStorage) IGrule {
+func New(cfg config.Grule, store storage.IRulesetStorage, pipelines storage.IPipelineStorage) IGrule {
 
 	engine := engine.NewSingleEngine(engine.Config{
 		Type:            cfg.GetType(),
 		Size:            cfg.Size,
 		CleanupInterval: cfg.CleanupInterval,
 		TTL:             cfg.TTL,
-		FactName:        "Fact",
+		FactName:        factName,
 	})
 
 	return &grule{
-		cfg:    cfg,
-		store:  store,
-		engine: engine,
+		cfg:       cfg,
+		store:     store,
+		pipelines: pipelines,
+		engine:    engine,
 	}
 }
 
+// Warmup compiles every stored ruleset into the engine
+// so that the first evaluations after a restart do not pay the build cost
+func (g *grule) Warmup(ctx context.Context) error {
+
+	rules, err := g.store.GetAll(ctx)
+	if err != nil {
+		return err
+	}
+
+	metrics.Rulesets.Set(float64(len(rules)))
+
+	loaded := 0
+	for _, rule := range rules {
+		if err := g.engine.BuildRule(rule.Name, rule.GRL, 0); err != nil {
+			logger.WithContext(ctx).Errorf("Failed to build rule %v: %v", rule.Name, err)
+			continue
+		}
+		g.built.Store(rule.Name, struct{}{})
+		loaded++
+	}
+
+	logger.WithContext(ctx).Infof("Loaded %d/%d rulesets into the engine", loaded, len(rules))
+
+	return nil
+}
+
 // Evaluate evaluates the ruleset with the given facts
 func (g *grule) Evaluate(ctx context.Context, in dto.EvaluateIn) (*dto.EvaluateOut, error) {
 
-	rule, err := g.store.GetByName(ctx, in.RuleName)
+	rule, err := g.lookup(ctx, in.RuleName)
 	if err != nil {
 		return nil, err
 	}
 
+	if err := g.checkFacts(rule, in.Facts.M); err != nil {
+		return nil, err
+	}
+
+	defer observeEvaluate(rule.Name, time.Now())
+
+	// the engine cache does not accept listeners, a traced evaluation compiles the ruleset on its own
+	if in.Trace {
+		library, err := compile(rule.GRL)
+		if err != nil {
+			return nil, err
+		}
+		t, err := run(ctx, library, &in.Facts, true)
+		if err != nil {
+			return nil, err
+		}
+		return &dto.EvaluateOut{ModifiedFacts: in.Facts.AsMap(), Trace: t}, nil
+	}
+
 	err = g.engine.Execute(ctx, rule.Name, &in.Facts)
 	if err != nil {
-		return nil, err
+		return nil, executionError(err)
 	}
 
 	return &dto.EvaluateOut{ModifiedFacts: in.Facts.AsMap()}, nil
@@ -64,13 +153,28 @@ func (g *grule) Evaluate(ctx context.Context, in dto.EvaluateIn) (*dto.EvaluateO
 // Create creates a new ruleset
 func (g *grule) Create(ctx context.Context, in dto.CreateIn) (*dto.CreateOut, error) {
 
+	if _, err := compile(in.GRL); err != nil {
+		return nil, err
+	}
+
+	schema, err := normalizeSchema(in.FactSchema)
+	if err != nil {
+		return nil, err
+	}
+
 	rule := storage.Ruleset{
 		Name:        in.Name,
 		Description: in.Description,
 		Salience:    in.Salience,
 		GRL:         in.GRL,
+		Tags:        normalizeTags(in.Tags),
+		FactSchema:  schema,
+		UpdatedBy:   in.Author,
 	}
 
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
 	id, err := g.store.Create(ctx, rule)
 	if err != nil {
 		return nil, err
@@ -78,8 +182,16 @@ func (g *grule) Create(ctx context.Context, in dto.CreateIn) (*dto.CreateOut, er
 
 	err = g.engine.AddRule(rule.Name, rule.GRL, 0)
 	if err != nil {
-		logger.Errorf("Failed to add rule %v: %v", rule.Name, err)
+		logger.WithContext(ctx).Errorf("Failed to add rule %v, rolling back: %v", rule.Name, err)
+		if rbErr := g.store.Delete(ctx, rule.Name); rbErr != nil {
+			logger.WithContext(ctx).Errorf("Failed to roll back rule %v: %v", rule.Name, rbErr)
+		}
+		return nil, err
 	}
+	g.built.Store(rule.Name, struct{}{})
+	metrics.Rulesets.Inc()
+
+	g.notify(ctx, RulesetCreated, rule.Name)
 
 	return &dto.CreateOut{ID: id}, nil
 }
@@ -87,36 +199,76 @@ func (g *grule) Create(ctx context.Context, in dto.CreateIn) (*dto.CreateOut, er
 // Update updates an existing ruleset
 func (g *grule) Update(ctx context.Context, name string, in dto.UpdateIn) (*dto.UpdateOut, error) {
 
-	rule, err := g.store.GetByName(ctx, name)
+	if _, err := compile(in.GRL); err != nil {
+		return nil, err
+	}
+
+	schema, err := normalizeSchema(in.FactSchema)
 	if err != nil {
 		return nil, err
 	}
 
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	prev, err := g.store.GetByName(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	if in.ExpectedVersion != 0 && in.ExpectedVersion != prev.Version {
+		return nil, fmt.Errorf("%w: ruleset %s is at version %d, expected version %d",
+			storage.ErrConflict, name, prev.Version, in.ExpectedVersion)
+	}
+
+	rule := *prev
 	rule.Description = in.Description
 	rule.Salience = in.Salience
 	rule.GRL = in.GRL
+	rule.UpdatedBy = in.Author
+	if in.Tags != nil {
+		rule.Tags = normalizeTags(in.Tags)
+	}
+	if in.FactSchema != nil {
+		rule.FactSchema = schema
+	}
 
-	err = g.store.Update(ctx, name, *rule)
+	// the engine is updated once the ruleset is stored, so that evaluations never run GRL that is not.
+	// AddRule replaces the compiled rule where BuildRule would keep a cached stale one
+	err = g.store.Update(ctx, name, rule)
 	if err != nil {
 		return nil, err
 	}
 
-	err = g.engine.BuildRule(rule.Name, rule.GRL, 0)
+	err = g.engine.AddRule(rule.Name, rule.GRL, 0)
 	if err != nil {
-		logger.Errorf("Failed to build rule %v: %v", rule.Name, err)
+		// the GRL compiled above, should it still fail the stored ruleset is built again on its next evaluation
+		logger.WithContext(ctx).Errorf("Failed to add rule %v: %v", rule.Name, err)
+		g.engine.RemoveRule(name)
 	}
 
-	return &dto.UpdateOut{Success: true}, nil
+	g.notify(ctx, RulesetUpdated, name)
+
+	return &dto.UpdateOut{Success: true, Version: rule.Version + 1}, nil
 }
 
 // Delete deletes a ruleset by name
 func (g *grule) Delete(ctx context.Context, name string) (*dto.DeleteOut, error) {
 
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
 	err := g.store.Delete(ctx, name)
 	if err != nil {
 		return nil, err
 	}
 
+	g.engine.RemoveRule(name)
+	g.schemas.Delete(name)
+	g.built.Delete(name)
+	metrics.Rulesets.Dec()
+	g.notify(ctx, RulesetDeleted, name)
+
 	return &dto.DeleteOut{Success: true}, nil
 }
 
@@ -141,3 +293,132 @@ func (g *grule) GetByName(ctx context.Context, name string) (*dto.GetByNameOut,
 
 	return &dto.GetByNameOut{Ruleset: *rule}, nil
 }
+
+// History retrieves the revisions of a ruleset
+func (g *grule) History(ctx context.Context, name string) (*dto.HistoryOut, error) {
+
+	revisions, err := g.store.GetRevisions(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.HistoryOut{Revisions: revisions}, nil
+}
+
+// Rollback restores a previous revision of a ruleset as a new revision, it fails with ErrConflict
+// when the ruleset is updated meanwhile
+func (g *grule) Rollback(ctx context.Context, name string, in dto.RollbackIn) (*dto.RollbackOut, error) {
+
+	current, err := g.store.GetByName(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	rev, err := g.store.GetRevision(ctx, name, in.Revision)
+	if err != nil {
+		return nil, err
+	}
+
+	// an empty list and an empty schema remove the current tags and schema, where nil would keep them
+	tags, schema := rev.Tags, rev.FactSchema
+	if tags == nil {
+		tags = []string{}
+	}
+	if schema == nil {
+		schema = map[string]any{}
+	}
+
+	_, err = g.Update(ctx, name, dto.UpdateIn{
+		Name:            name,
+		Description:     rev.Description,
+		Salience:        rev.Salience,
+		GRL:             rev.GRL,
+		Tags:            tags,
+		FactSchema:      schema,
+		Author:          in.Author,
+		ExpectedVersion: current.Version,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.RollbackOut{Success: true}, nil
+}
+
+// Try evaluates an inline GRL script against the given facts without storing it,
+// a script that does not compile is reported in the output rather than as an error
+func (g *grule) Try(ctx context.Context, in dto.TryIn) (*dto.TryOut, error) {
+
+	library, err := compile(in.GRL)
+	var compileErr *CompileError
+	if errors.As(err, &compileErr) {
+		return &dto.TryOut{Errors: compileErr.Errors}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	t, err := run(ctx, library, &in.Facts, in.Trace)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.TryOut{ModifiedFacts: in.Facts.AsMap(), Trace: t}, nil
+}
+
+// lookup reads a ruleset and makes sure it is compiled in the engine,
+// the read lock prevents loading a ruleset that is being deleted
+func (g *grule) lookup(ctx context.Context, name string) (*storage.Ruleset, error) {
+
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	rule, err := g.store.GetByName(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	err = g.load(rule)
+	if err != nil {
+		return nil, err
+	}
+
+	return rule, nil
+}
+
+// load builds the ruleset into the engine when it is not cached,
+// e.g. after a restart or when the cache evicted it
+func (g *grule) load(rule *storage.Ruleset) error {
+
+	if g.engine.ContainsRule(rule.Name) {
+		metrics.CacheHits.Inc()
+		return nil
+	}
+
+	metrics.CacheMisses.Inc()
+	if _, evicted := g.built.Load(rule.Name); evicted {
+		metrics.CacheEvictions.Inc()
+	}
+
+	if err := g.engine.BuildRule(rule.Name, rule.GRL, 0); err != nil {
+		return err
+	}
+	g.built.Store(rule.Name, struct{}{})
+
+	return nil
+}
+
+// observeEvaluate records the duration of an evaluation of a ruleset started at start
+func observeEvaluate(name string, start time.Time) {
+	metrics.EvaluateDuration.WithLabelValues(name).Observe(time.Since(start).Seconds())
+}
+
+// executionError wraps an error of the engine executing rules, nil stays nil
+func executionError(err error) error {
+
+	if err == nil {
+		return nil
+	}
+
+	return fmt.Errorf("%w: %w", ErrExecution, err)
+}
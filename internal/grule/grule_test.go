@@ -0,0 +1,102 @@
+package grule
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/hungpdn/mcp2grule/internal/api/dto"
+	"github.com/hungpdn/mcp2grule/internal/config"
+	"github.com/hungpdn/mcp2grule/internal/storage"
+)
+
+// racingStore updates a ruleset right after a revision of it is read, like a concurrent writer would
+type racingStore struct {
+	storage.IRulesetStorage
+	race func()
+}
+
+func (s *racingStore) GetRevision(ctx context.Context, name string, revision int) (*storage.Revision, error) {
+	rev, err := s.IRulesetStorage.GetRevision(ctx, name, revision)
+	if s.race != nil {
+		s.race()
+	}
+	return rev, err
+}
+
+func TestRollback(t *testing.T) {
+
+	ctx := context.Background()
+	schema := map[string]any{
+		"type":       "object",
+		"properties": map[string]any{"amount": map[string]any{"type": "number"}},
+	}
+	const v1 = `rule A "" salience 10 { when true then Fact.Set("v", 1); Retract("A"); }`
+	const v2 = `rule A "" salience 10 { when true then Fact.Set("v", 2); Retract("A"); }`
+
+	tests := []struct {
+		name  string
+		race  bool // whether the ruleset is updated between reading the revision and restoring it
+		err   error
+		check func(t *testing.T, rule *storage.Ruleset)
+	}{
+		{
+			name: "restores the GRL, tags and fact schema of the revision",
+			check: func(t *testing.T, rule *storage.Ruleset) {
+				if rule.GRL != v1 || rule.Description != "first" || rule.Version != 3 {
+					t.Fatalf("ruleset %q %q version %d, want revision 1 restored as version 3", rule.GRL, rule.Description, rule.Version)
+				}
+				if !reflect.DeepEqual(rule.Tags, []string{"pricing"}) {
+					t.Fatalf("tags %v, want [pricing]", rule.Tags)
+				}
+				if !reflect.DeepEqual(rule.FactSchema, schema) {
+					t.Fatalf("fact schema %v, want %v", rule.FactSchema, schema)
+				}
+			},
+		},
+		{
+			name: "concurrent update",
+			race: true,
+			err:  storage.ErrConflict,
+			check: func(t *testing.T, rule *storage.Ruleset) {
+				if rule.GRL != v2 || rule.Version != 3 {
+					t.Fatalf("ruleset %q version %d, want the concurrent update kept", rule.GRL, rule.Version)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &racingStore{IRulesetStorage: storage.NewMemory()}
+			g := New(config.Grule{}, store, storage.NewMemory())
+
+			_, err := g.Create(ctx, dto.CreateIn{Name: "loan", Description: "first", GRL: v1, Tags: []string{"pricing"}, FactSchema: schema})
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = g.Update(ctx, "loan", dto.UpdateIn{Name: "loan", Description: "second", GRL: v2, Tags: []string{"risk"}, FactSchema: map[string]any{}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.race {
+				store.race = func() {
+					if _, err := g.Update(ctx, "loan", dto.UpdateIn{Name: "loan", Description: "third", GRL: v2}); err != nil {
+						t.Fatal(err)
+					}
+				}
+			}
+
+			_, err = g.Rollback(ctx, "loan", dto.RollbackIn{Name: "loan", Revision: 1})
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Rollback() = %v, want %v", err, tt.err)
+			}
+
+			rule, err := store.GetByName(ctx, "loan")
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.check(t, rule)
+		})
+	}
+}
@@ -0,0 +1,169 @@
+package grule
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/hungpdn/mcp2grule/internal/api/dto"
+	"github.com/hungpdn/mcp2grule/internal/storage"
+)
+
+// EvaluatePipeline evaluates the stages of a pipeline in order on the same facts,
+// each stage runs its ruleset through the engine cache like Evaluate
+func (g *grule) EvaluatePipeline(ctx context.Context, in dto.EvaluatePipelineIn) (*dto.EvaluatePipelineOut, error) {
+
+	pipeline, err := g.pipelines.GetPipeline(ctx, in.Pipeline)
+	if err != nil {
+		return nil, err
+	}
+
+	if in.Facts.M == nil {
+		in.Facts.M = map[string]any{}
+	}
+
+	out := &dto.EvaluatePipelineOut{Stages: make([]dto.StageResult, 0, len(pipeline.Stages))}
+	for i, stage := range pipeline.Stages {
+		res, err := g.Evaluate(ctx, dto.EvaluateIn{Facts: in.Facts, RuleName: stage.Ruleset, Trace: in.Trace})
+		if err != nil {
+			return nil, fmt.Errorf("stage %d (%s): %w", i+1, stage.Ruleset, err)
+		}
+
+		result := dto.StageResult{Ruleset: stage.Ruleset, Trace: res.Trace}
+		result.Stopped = holds(stage.StopWhen, in.Facts.M)
+		out.Stages = append(out.Stages, result)
+
+		if result.Stopped {
+			break
+		}
+	}
+
+	out.ModifiedFacts = in.Facts.AsMap()
+	return out, nil
+}
+
+// CreatePipeline creates a new pipeline
+func (g *grule) CreatePipeline(ctx context.Context, in dto.CreatePipelineIn) (*dto.CreatePipelineOut, error) {
+
+	if err := g.validateStages(ctx, in.Stages); err != nil {
+		return nil, err
+	}
+
+	id, err := g.pipelines.CreatePipeline(ctx, storage.Pipeline{
+		Name:        in.Name,
+		Description: in.Description,
+		Stages:      in.Stages,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.CreatePipelineOut{ID: id}, nil
+}
+
+// UpdatePipeline updates an existing pipeline
+func (g *grule) UpdatePipeline(ctx context.Context, name string, in dto.UpdatePipelineIn) (*dto.UpdatePipelineOut, error) {
+
+	if err := g.validateStages(ctx, in.Stages); err != nil {
+		return nil, err
+	}
+
+	err := g.pipelines.UpdatePipeline(ctx, name, storage.Pipeline{
+		Name:        name,
+		Description: in.Description,
+		Stages:      in.Stages,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.UpdatePipelineOut{Success: true}, nil
+}
+
+// DeletePipeline deletes a pipeline by name
+func (g *grule) DeletePipeline(ctx context.Context, name string) (*dto.DeletePipelineOut, error) {
+
+	err := g.pipelines.DeletePipeline(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.DeletePipelineOut{Success: true}, nil
+}
+
+// GetAllPipelines retrieves all pipelines
+func (g *grule) GetAllPipelines(ctx context.Context) (*dto.GetAllPipelinesOut, error) {
+
+	pipelines, err := g.pipelines.GetAllPipelines(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.GetAllPipelinesOut{Pipelines: pipelines}, nil
+}
+
+// GetPipeline retrieves a pipeline by name
+func (g *grule) GetPipeline(ctx context.Context, name string) (*dto.GetPipelineOut, error) {
+
+	pipeline, err := g.pipelines.GetPipeline(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.GetPipelineOut{Pipeline: *pipeline}, nil
+}
+
+// validateStages checks that a pipeline has stages and that each one names an existing ruleset,
+// a ruleset deleted later makes the pipeline fail at that stage
+func (g *grule) validateStages(ctx context.Context, stages []storage.Stage) error {
+
+	if len(stages) == 0 {
+		return fmt.Errorf("%w: a pipeline needs at least one stage", storage.ErrInvalidInput)
+	}
+
+	for i, stage := range stages {
+		if stage.StopWhen != nil && stage.StopWhen.Key == "" {
+			return fmt.Errorf("%w: stage %d: stop condition without a key", storage.ErrInvalidInput, i+1)
+		}
+
+		_, err := g.store.GetByName(ctx, stage.Ruleset)
+		if errors.Is(err, storage.ErrNotFound) {
+			return fmt.Errorf("%w: stage %d: ruleset %q does not exist", storage.ErrInvalidInput, i+1, stage.Ruleset)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// holds reports whether a stop condition is met by the facts,
+// values are compared by their JSON encoding so that 2 and 2.0 are equal
+func holds(cond *storage.StopCondition, facts map[string]any) bool {
+
+	if cond == nil {
+		return false
+	}
+
+	v, ok := facts[cond.Key]
+	if !ok {
+		return false
+	}
+	if cond.Equals == nil {
+		return v != nil
+	}
+
+	want, err := json.Marshal(cond.Equals)
+	if err != nil {
+		return false
+	}
+	got, err := json.Marshal(v)
+	if err != nil {
+		return false
+	}
+
+	return bytes.Equal(want, got)
+}
@@ -0,0 +1,182 @@
+package grule
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/hungpdn/mcp2grule/internal/api/dto"
+	"github.com/hungpdn/mcp2grule/internal/config"
+	"github.com/hungpdn/mcp2grule/internal/storage"
+)
+
+func TestEvaluatePipeline(t *testing.T) {
+
+	ctx := context.Background()
+	rulesets := map[string]string{
+		"score":   `rule Score "" salience 1 { when Fact.Get("income") > 1000 then Fact.Set("score", 10); Retract("Score"); }`,
+		"approve": `rule Approve "" salience 1 { when Fact.Get("score") > 5 then Fact.Set("approved", true); Retract("Approve"); }`,
+		"notify":  `rule Notify "" salience 1 { when true then Fact.Set("notified", true); Retract("Notify"); }`,
+	}
+
+	tests := []struct {
+		name     string
+		stages   []storage.Stage
+		facts    map[string]any
+		deleted  string   // ruleset deleted after the pipeline is created
+		executed []string // rulesets of the executed stages, in order
+		stopped  bool     // whether the last executed stage stopped the pipeline
+		want     map[string]any
+		err      string // part of the error, empty when the evaluation succeeds
+	}{
+		{
+			name:     "facts carry from one stage to the next",
+			stages:   []storage.Stage{{Ruleset: "score"}, {Ruleset: "approve"}, {Ruleset: "notify"}},
+			facts:    map[string]any{"income": 2000},
+			executed: []string{"score", "approve", "notify"},
+			want:     map[string]any{"income": 2000, "score": int64(10), "approved": true, "notified": true},
+		},
+		{
+			name: "stop_when with a value",
+			stages: []storage.Stage{
+				{Ruleset: "score"},
+				{Ruleset: "approve", StopWhen: &storage.StopCondition{Key: "approved", Equals: true}},
+				{Ruleset: "notify"},
+			},
+			facts:    map[string]any{"income": 2000},
+			executed: []string{"score", "approve"},
+			stopped:  true,
+			want:     map[string]any{"income": 2000, "score": int64(10), "approved": true},
+		},
+		{
+			name: "stop_when comparing numbers by value",
+			stages: []storage.Stage{
+				{Ruleset: "score", StopWhen: &storage.StopCondition{Key: "score", Equals: 10.0}},
+				{Ruleset: "approve"},
+			},
+			facts:    map[string]any{"income": 2000},
+			executed: []string{"score"},
+			stopped:  true,
+			want:     map[string]any{"income": 2000, "score": int64(10)},
+		},
+		{
+			name: "stop_when a key is set",
+			stages: []storage.Stage{
+				{Ruleset: "score", StopWhen: &storage.StopCondition{Key: "score"}},
+				{Ruleset: "approve"},
+			},
+			facts:    map[string]any{"income": 2000},
+			executed: []string{"score"},
+			stopped:  true,
+			want:     map[string]any{"income": 2000, "score": int64(10)},
+		},
+		{
+			name: "stop_when a key is not set",
+			stages: []storage.Stage{
+				{Ruleset: "score", StopWhen: &storage.StopCondition{Key: "score"}},
+				{Ruleset: "approve"},
+			},
+			facts:    map[string]any{"income": 500},
+			executed: []string{"score", "approve"},
+			want:     map[string]any{"income": 500},
+		},
+		{
+			name:    "missing stage ruleset",
+			stages:  []storage.Stage{{Ruleset: "score"}, {Ruleset: "approve"}, {Ruleset: "notify"}},
+			facts:   map[string]any{"income": 2000},
+			deleted: "approve",
+			err:     "stage 2 (approve)",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := storage.NewMemory()
+			g := New(config.Grule{}, store, store)
+			for _, name := range []string{"score", "approve", "notify"} {
+				if _, err := g.Create(ctx, dto.CreateIn{Name: name, GRL: rulesets[name]}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if _, err := g.CreatePipeline(ctx, dto.CreatePipelineIn{Name: "loan", Stages: tt.stages}); err != nil {
+				t.Fatal(err)
+			}
+			if tt.deleted != "" {
+				if _, err := g.Delete(ctx, tt.deleted); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			out, err := g.EvaluatePipeline(ctx, dto.EvaluatePipelineIn{Pipeline: "loan", Facts: *dto.NewFact(tt.facts)})
+			if tt.err != "" {
+				if !errors.Is(err, storage.ErrNotFound) || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("EvaluatePipeline() = %v, want not found containing %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			executed := make([]string, 0, len(out.Stages))
+			for i, stage := range out.Stages {
+				executed = append(executed, stage.Ruleset)
+				if last := i == len(out.Stages)-1; stage.Stopped != (last && tt.stopped) {
+					t.Errorf("stage %d stopped %v, want %v", i+1, stage.Stopped, last && tt.stopped)
+				}
+			}
+			if !slices.Equal(executed, tt.executed) {
+				t.Errorf("executed stages %v, want %v", executed, tt.executed)
+			}
+			if len(out.ModifiedFacts) != len(tt.want) {
+				t.Fatalf("facts %v, want %v", out.ModifiedFacts, tt.want)
+			}
+			for k, v := range tt.want {
+				if out.ModifiedFacts[k] != v {
+					t.Fatalf("facts %v, want %v", out.ModifiedFacts, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestPipelineErrors(t *testing.T) {
+
+	ctx := context.Background()
+	store := storage.NewMemory()
+	g := New(config.Grule{}, store, store)
+	if _, err := g.Create(ctx, dto.CreateIn{Name: "score", GRL: echo}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		call func() error
+		want error
+	}{
+		{"create without stages", func() error {
+			_, err := g.CreatePipeline(ctx, dto.CreatePipelineIn{Name: "p"})
+			return err
+		}, storage.ErrInvalidInput},
+		{"create with a missing ruleset", func() error {
+			_, err := g.CreatePipeline(ctx, dto.CreatePipelineIn{Name: "p", Stages: []storage.Stage{{Ruleset: "score"}, {Ruleset: "nope"}}})
+			return err
+		}, storage.ErrInvalidInput},
+		{"create with a stop condition without key", func() error {
+			_, err := g.CreatePipeline(ctx, dto.CreatePipelineIn{Name: "p", Stages: []storage.Stage{{Ruleset: "score", StopWhen: &storage.StopCondition{}}}})
+			return err
+		}, storage.ErrInvalidInput},
+		{"evaluate a missing pipeline", func() error {
+			_, err := g.EvaluatePipeline(ctx, dto.EvaluatePipelineIn{Pipeline: "nope"})
+			return err
+		}, storage.ErrNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.call(); !errors.Is(err, tt.want) {
+				t.Fatalf("%s = %v, want %v", tt.name, err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,160 @@
+package grule
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/google/jsonschema-go/jsonschema"
+	"github.com/hungpdn/mcp2grule/internal/api/dto"
+	"github.com/hungpdn/mcp2grule/internal/storage"
+)
+
+// FactsError is returned when facts do not match the fact schema of a ruleset
+type FactsError struct {
+	Ruleset string
+	Issues  []string // Unknown and missing facts, the usual mistakes, spelled out
+	Err     error
+}
+
+// Error implements the error interface
+func (e *FactsError) Error() string {
+	issues := append(slices.Clone(e.Issues), e.Err.Error())
+	return fmt.Sprintf("facts do not match the fact schema of ruleset %s: %s", e.Ruleset, strings.Join(issues, "; "))
+}
+
+// Unwrap returns the validation error
+func (e *FactsError) Unwrap() error {
+	return e.Err
+}
+
+// resolvedSchema is a fact schema resolved for a version of a ruleset
+type resolvedSchema struct {
+	version  int
+	resolved *jsonschema.Resolved
+}
+
+// Schema returns the fact schema of a ruleset and the input schema of grule.evaluate for it
+func (g *grule) Schema(ctx context.Context, name string) (*dto.SchemaOut, error) {
+
+	rule, err := g.store.GetByName(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	facts := rule.FactSchema
+	if facts == nil {
+		facts = map[string]any{"type": "object"}
+	}
+
+	return &dto.SchemaOut{
+		Name:       rule.Name,
+		FactSchema: rule.FactSchema,
+		InputSchema: map[string]any{
+			"type":     "object",
+			"required": []string{"rule_name", "facts"},
+			"properties": map[string]any{
+				"rule_name": map[string]any{"const": rule.Name},
+				"facts": map[string]any{
+					"type":       "object",
+					"required":   []string{"M"},
+					"properties": map[string]any{"M": facts},
+				},
+				"trace": map[string]any{"type": "boolean"},
+			},
+		},
+	}, nil
+}
+
+// checkFacts validates facts against the fact schema of a ruleset, if it has one
+func (g *grule) checkFacts(rule *storage.Ruleset, facts map[string]any) error {
+
+	if rule.FactSchema == nil {
+		return nil
+	}
+
+	var resolved *jsonschema.Resolved
+	if v, ok := g.schemas.Load(rule.Name); ok && v.(resolvedSchema).version == rule.Version {
+		resolved = v.(resolvedSchema).resolved
+	} else {
+		r, err := resolveSchema(rule.FactSchema)
+		if err != nil {
+			return err
+		}
+		g.schemas.Store(rule.Name, resolvedSchema{version: rule.Version, resolved: r})
+		resolved = r
+	}
+
+	if facts == nil {
+		facts = map[string]any{}
+	}
+	if err := resolved.Validate(facts); err != nil {
+		return &FactsError{Ruleset: rule.Name, Issues: factIssues(rule.FactSchema, facts), Err: err}
+	}
+
+	return nil
+}
+
+// factIssues lists the facts not declared by a schema closed with additionalProperties false,
+// then the required facts that are missing
+func factIssues(schema map[string]any, facts map[string]any) []string {
+
+	var issues []string
+
+	properties, _ := schema["properties"].(map[string]any)
+	if closed, ok := schema["additionalProperties"].(bool); ok && !closed {
+		for _, key := range slices.Sorted(maps.Keys(facts)) {
+			if _, ok := properties[key]; !ok {
+				issues = append(issues, fmt.Sprintf("unknown fact %q", key))
+			}
+		}
+	}
+
+	required, _ := schema["required"].([]any)
+	for _, key := range required {
+		if k, ok := key.(string); ok {
+			if _, ok := facts[k]; !ok {
+				issues = append(issues, fmt.Sprintf("missing required fact %q", k))
+			}
+		}
+	}
+
+	return issues
+}
+
+// normalizeSchema checks that a fact schema is a valid JSON Schema describing an object,
+// an empty schema accepts any facts and is dropped
+func normalizeSchema(schema map[string]any) (map[string]any, error) {
+
+	if len(schema) == 0 {
+		return nil, nil
+	}
+
+	if _, err := resolveSchema(schema); err != nil {
+		return nil, fmt.Errorf("%w: fact schema: %v", storage.ErrInvalidInput, err)
+	}
+	if t, ok := schema["type"]; ok && t != "object" {
+		return nil, fmt.Errorf("%w: fact schema must describe an object, got type %v", storage.ErrInvalidInput, t)
+	}
+
+	return schema, nil
+}
+
+// resolveSchema parses and resolves a fact schema, remote references are not allowed
+func resolveSchema(schema map[string]any) (*jsonschema.Resolved, error) {
+
+	b, err := json.Marshal(schema)
+	if err != nil {
+		return nil, err
+	}
+
+	var s jsonschema.Schema
+	if err := json.Unmarshal(b, &s); err != nil {
+		return nil, err
+	}
+
+	return s.Resolve(nil)
+}
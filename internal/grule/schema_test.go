@@ -0,0 +1,119 @@
+package grule
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/hungpdn/mcp2grule/internal/api/dto"
+	"github.com/hungpdn/mcp2grule/internal/config"
+	"github.com/hungpdn/mcp2grule/internal/storage"
+)
+
+func TestNormalizeSchema(t *testing.T) {
+
+	object := map[string]any{"type": "object", "properties": map[string]any{"amount": map[string]any{"type": "number"}}}
+
+	tests := []struct {
+		name   string
+		schema map[string]any
+		want   map[string]any
+		err    error
+	}{
+		{"nil schema is dropped", nil, nil, nil},
+		{"empty schema is dropped", map[string]any{}, nil, nil},
+		{"object schema", object, object, nil},
+		{"schema without type", map[string]any{"required": []any{"amount"}}, map[string]any{"required": []any{"amount"}}, nil},
+		{"not an object", map[string]any{"type": "array"}, nil, storage.ErrInvalidInput},
+		{"unknown type", map[string]any{"type": "decimal"}, nil, storage.ErrInvalidInput},
+		{"malformed keyword", map[string]any{"properties": "amount"}, nil, storage.ErrInvalidInput},
+		{"remote reference", map[string]any{"$ref": "https://example.com/facts.json"}, nil, storage.ErrInvalidInput},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := normalizeSchema(tt.schema)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("normalizeSchema() = %v, want %v", err, tt.err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("normalizeSchema() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckFacts(t *testing.T) {
+
+	ctx := context.Background()
+	store := storage.NewMemory()
+	g := New(config.Grule{}, store, store)
+
+	schema := map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"amount": map[string]any{"type": "number", "minimum": 0},
+			"tier":   map[string]any{"enum": []any{"gold", "silver"}},
+		},
+		"required":             []any{"amount"},
+		"additionalProperties": false,
+	}
+	grl := `rule A "" salience 1 { when true then Fact.Set("checked", true); Retract("A"); }`
+	if _, err := g.Create(ctx, dto.CreateIn{Name: "loan", GRL: grl, FactSchema: schema}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		facts  map[string]any
+		ok     bool
+		issues []string // issues spelled out before the validation error
+	}{
+		{"conforming facts", map[string]any{"amount": 10.5, "tier": "gold"}, true, nil},
+		{"wrong type", map[string]any{"amount": "ten"}, false, nil},
+		{"out of range", map[string]any{"amount": -1}, false, nil},
+		{"not in enum", map[string]any{"amount": 1, "tier": "bronze"}, false, nil},
+		{"missing required fact", map[string]any{"tier": "gold"}, false, []string{`missing required fact "amount"`}},
+		{"unknown fact", map[string]any{"amount": 1, "score": 3}, false, []string{`unknown fact "score"`}},
+		{"no facts", nil, false, []string{`missing required fact "amount"`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := g.Evaluate(ctx, dto.EvaluateIn{RuleName: "loan", Facts: *dto.NewFact(tt.facts)})
+			if tt.ok {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if out.ModifiedFacts["checked"] != true {
+					t.Fatalf("facts %v, want the ruleset evaluated", out.ModifiedFacts)
+				}
+				return
+			}
+
+			var factsErr *FactsError
+			if !errors.As(err, &factsErr) {
+				t.Fatalf("Evaluate() = %v, want a FactsError", err)
+			}
+			if factsErr.Ruleset != "loan" || !slices.Equal(factsErr.Issues, tt.issues) {
+				t.Fatalf("FactsError of %s with issues %q, want loan with %q", factsErr.Ruleset, factsErr.Issues, tt.issues)
+			}
+		})
+	}
+}
+
+func TestCreateRejectsInvalidSchema(t *testing.T) {
+
+	ctx := context.Background()
+	store := storage.NewMemory()
+	g := New(config.Grule{}, store, store)
+
+	grl := `rule A "" salience 1 { when true then Retract("A"); }`
+	_, err := g.Create(ctx, dto.CreateIn{Name: "loan", GRL: grl, FactSchema: map[string]any{"type": "string"}})
+	if !errors.Is(err, storage.ErrInvalidInput) {
+		t.Fatalf("Create() = %v, want %v", err, storage.ErrInvalidInput)
+	}
+	if _, err := store.GetByName(ctx, "loan"); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("GetByName() = %v, want the ruleset not stored", err)
+	}
+}
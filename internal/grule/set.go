@@ -0,0 +1,189 @@
+package grule
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/hungpdn/grule-plus/engine"
+	"github.com/hungpdn/mcp2grule/internal/api/dto"
+	"github.com/hungpdn/mcp2grule/internal/storage"
+	"github.com/hyperjumptech/grule-rule-engine/ast"
+)
+
+// EvaluateSet evaluates several rulesets merged into one knowledge base,
+// the rules of a ruleset with a higher salience take precedence over those of a lower one
+func (g *grule) EvaluateSet(ctx context.Context, in dto.EvaluateSetIn) (*dto.EvaluateSetOut, error) {
+
+	rules, err := g.selectSet(ctx, in.RuleNames, in.Tag)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range rules {
+		if err := g.checkFacts(&rules[i], in.Facts.M); err != nil {
+			return nil, err
+		}
+	}
+
+	library, err := compose(rules)
+	if err != nil {
+		return nil, err
+	}
+
+	if in.Facts.M == nil {
+		in.Facts.M = map[string]any{}
+	}
+
+	t, err := run(ctx, library, &in.Facts, in.Trace)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(rules))
+	for _, rule := range rules {
+		names = append(names, rule.Name)
+	}
+
+	return &dto.EvaluateSetOut{ModifiedFacts: in.Facts.AsMap(), Rulesets: names, Trace: t}, nil
+}
+
+// selectSet reads the rulesets named or tagged, highest salience first
+func (g *grule) selectSet(ctx context.Context, names []string, tag string) ([]storage.Ruleset, error) {
+
+	if (len(names) == 0) == (tag == "") {
+		return nil, fmt.Errorf("%w: exactly one of rule_names or tag is required", storage.ErrInvalidInput)
+	}
+
+	var rules []storage.Ruleset
+	if tag != "" {
+		all, err := g.store.GetAll(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, rule := range all {
+			if slices.Contains(rule.Tags, tag) {
+				rules = append(rules, rule)
+			}
+		}
+		if len(rules) == 0 {
+			return nil, fmt.Errorf("%w: no ruleset tagged %q", storage.ErrNotFound, tag)
+		}
+	} else {
+		for _, name := range slices.Compact(slices.Sorted(slices.Values(names))) {
+			rule, err := g.store.GetByName(ctx, name)
+			if err != nil {
+				return nil, fmt.Errorf("ruleset %s: %w", name, err)
+			}
+			rules = append(rules, *rule)
+		}
+	}
+
+	sort.SliceStable(rules, func(i, j int) bool {
+		if rules[i].Salience != rules[j].Salience {
+			return rules[i].Salience > rules[j].Salience
+		}
+		return rules[i].Name < rules[j].Name
+	})
+
+	return rules, nil
+}
+
+// compose builds rulesets into one knowledge library. The salience of every rule is
+// rewritten to ruleset salience * stride + rule salience, where the stride exceeds the spread
+// of the rule saliences, so that rulesets are ordered by their salience first and their rules keep
+// their relative order within a ruleset
+func compose(rules []storage.Ruleset) (*ast.KnowledgeLibrary, error) {
+
+	library := ast.NewKnowledgeLibrary()
+
+	owner := map[string]storage.Ruleset{} // rule entry name to its ruleset
+	for _, rule := range rules {
+		if err := build(library, rule.GRL); err != nil {
+			return nil, composeError(rule, owner, err)
+		}
+
+		kb := library.GetKnowledgeBase(engine.LibraryName, engine.LibraryVersion)
+		for name := range kb.RuleEntries {
+			if _, ok := owner[name]; !ok {
+				owner[name] = rule
+			}
+		}
+	}
+
+	kb := library.GetKnowledgeBase(engine.LibraryName, engine.LibraryVersion)
+	if kb == nil || len(kb.RuleEntries) == 0 {
+		return nil, fmt.Errorf("%w: the rulesets do not declare any rule", storage.ErrInvalidInput)
+	}
+
+	lowest, highest := math.MaxInt, math.MinInt
+	for _, entry := range kb.RuleEntries {
+		lowest = min(lowest, entry.Salience)
+		highest = max(highest, entry.Salience)
+	}
+
+	stride := highest - lowest + 1
+	for name, entry := range kb.RuleEntries {
+		salience, ok := composedSalience(owner[name].Salience, stride, entry.Salience-lowest)
+		if !ok {
+			return nil, fmt.Errorf("%w: salience %d of ruleset %s times the spread %d of the rule saliences is out of the int32 range",
+				storage.ErrInvalidInput, owner[name].Salience, owner[name].Name, stride)
+		}
+		entry.Salience = salience
+	}
+
+	return library, nil
+}
+
+// composedSalience returns ruleset * stride + offset, false when it does not fit in an int32
+// like the saliences grule parses
+func composedSalience(ruleset, stride, offset int) (int, bool) {
+
+	if ruleset > math.MaxInt32/stride || ruleset < math.MinInt32/stride {
+		return 0, false
+	}
+
+	salience := ruleset*stride + offset
+	if salience > math.MaxInt32 || salience < math.MinInt32 {
+		return 0, false
+	}
+
+	return salience, true
+}
+
+// composeError tells why a ruleset could not join the rulesets composed before it, most likely
+// a rule name they also declare, which is a conflict between the rulesets rather than invalid GRL
+func composeError(rule storage.Ruleset, owner map[string]storage.Ruleset, err error) error {
+
+	library, compileErr := compile(rule.GRL)
+	if compileErr != nil {
+		return fmt.Errorf("%w: ruleset %s: %w", storage.ErrInvalidInput, rule.Name, compileErr)
+	}
+
+	kb := library.GetKnowledgeBase(engine.LibraryName, engine.LibraryVersion)
+	for name := range kb.RuleEntries {
+		if other, ok := owner[name]; ok {
+			return fmt.Errorf("%w: rule %s is declared by both ruleset %s and ruleset %s",
+				storage.ErrInvalidInput, name, other.Name, rule.Name)
+		}
+	}
+
+	return fmt.Errorf("%w: ruleset %s: %v", storage.ErrInvalidInput, rule.Name, err)
+}
+
+// normalizeTags trims, deduplicates and sorts tags, dropping empty ones
+func normalizeTags(tags []string) []string {
+
+	out := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			out = append(out, tag)
+		}
+	}
+	slices.Sort(out)
+
+	return slices.Compact(out)
+}
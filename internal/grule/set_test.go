@@ -0,0 +1,89 @@
+package grule
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/hungpdn/grule-plus/engine"
+	"github.com/hungpdn/mcp2grule/internal/storage"
+)
+
+func TestCompose(t *testing.T) {
+
+	rule := func(name string, salience int) string {
+		return fmt.Sprintf(`rule %s "" salience %d { when true then Retract("%s"); }`, name, salience, name)
+	}
+
+	tests := []struct {
+		name  string
+		rules []storage.Ruleset
+		want  []string // rule names by decreasing composed salience, nil when the composition fails
+		err   string   // part of the ErrInvalidInput message when the composition fails
+	}{
+		{
+			name: "rulesets ordered by their salience first",
+			rules: []storage.Ruleset{
+				{Name: "high", Salience: 2, GRL: rule("H1", 0) + rule("H2", -5)},
+				{Name: "low", Salience: 1, GRL: rule("L1", 100)},
+			},
+			want: []string{"H1", "H2", "L1"},
+		},
+		{
+			name: "negative ruleset salience",
+			rules: []storage.Ruleset{
+				{Name: "first", Salience: 0, GRL: rule("F", -10)},
+				{Name: "last", Salience: -3, GRL: rule("L", 10)},
+			},
+			want: []string{"F", "L"},
+		},
+		{
+			name:  "builder panic",
+			rules: []storage.Ruleset{{Name: "bad", GRL: outOfRange}},
+			err:   "out of range",
+		},
+		{
+			name: "rule declared twice",
+			rules: []storage.Ruleset{
+				{Name: "a", GRL: rule("R", 1)},
+				{Name: "b", GRL: rule("R", 2)},
+			},
+			err: "declared by both ruleset a and ruleset b",
+		},
+		{
+			name:  "ruleset salience out of range",
+			rules: []storage.Ruleset{{Name: "huge", Salience: 1 << 40, GRL: rule("R", 1)}},
+			err:   "out of the int32 range",
+		},
+		{
+			name: "rule salience spread out of range",
+			rules: []storage.Ruleset{
+				{Name: "wide", Salience: 1, GRL: rule("Low", -2000000000) + rule("High", 2000000000)},
+			},
+			err: "out of the int32 range",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			library, err := compose(tt.rules)
+			if tt.want == nil {
+				if !errors.Is(err, storage.ErrInvalidInput) || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("compose() = %v, want invalid input containing %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			kb := library.GetKnowledgeBase(engine.LibraryName, engine.LibraryVersion)
+			for i := 1; i < len(tt.want); i++ {
+				prev, next := kb.RuleEntries[tt.want[i-1]], kb.RuleEntries[tt.want[i]]
+				if prev.Salience <= next.Salience {
+					t.Fatalf("%s has salience %d, not above %s with %d", tt.want[i-1], prev.Salience, tt.want[i], next.Salience)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package grule
+
+import (
+	"context"
+	"reflect"
+	"sort"
+
+	"github.com/hungpdn/grule-plus/engine"
+	"github.com/hungpdn/mcp2grule/internal/api/dto"
+	"github.com/hyperjumptech/grule-rule-engine/ast"
+	gruleengine "github.com/hyperjumptech/grule-rule-engine/engine"
+)
+
+// run executes a compiled library in a throwaway engine, outside of the engine cache,
+// and records the fired rules when traced is set
+func run(ctx context.Context, library *ast.KnowledgeLibrary, facts *dto.Fact, traced bool) (*dto.Trace, error) {
+
+	kb, err := library.NewKnowledgeBaseInstance(engine.LibraryName, engine.LibraryVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	dataContext := ast.NewDataContext()
+	if err := dataContext.Add(factName, facts); err != nil {
+		return nil, err
+	}
+
+	e := gruleengine.NewGruleEngine()
+	if !traced {
+		return nil, executionError(e.ExecuteWithContext(ctx, dataContext, kb))
+	}
+
+	t := &tracer{facts: facts, out: &dto.Trace{Fired: make([]dto.FiredRule, 0)}}
+	e.Listeners = []gruleengine.GruleEngineListener{t}
+
+	err = e.ExecuteWithContext(ctx, dataContext, kb)
+	t.flush()
+	if err != nil {
+		return nil, executionError(err)
+	}
+
+	return t.out, nil
+}
+
+// tracer implements grule's GruleEngineListener,
+// the changes of a rule are the difference between the facts before it fires and the next cycle
+type tracer struct {
+	facts   *dto.Fact
+	out     *dto.Trace
+	matched []string
+	before  map[string]any
+	firing  *dto.FiredRule
+}
+
+// BeginCycle records the changes of the rule fired in the previous cycle
+func (t *tracer) BeginCycle(_ context.Context, _ uint64) {
+	t.flush()
+	t.matched = nil
+}
+
+// EvaluateRuleEntry collects the rules whose conditions matched in the cycle
+func (t *tracer) EvaluateRuleEntry(_ context.Context, _ uint64, entry *ast.RuleEntry, candidate bool) {
+	if candidate {
+		t.matched = append(t.matched, entry.RuleName)
+	}
+}
+
+// ExecuteRuleEntry is called right before the engine fires a rule
+func (t *tracer) ExecuteRuleEntry(_ context.Context, cycle uint64, entry *ast.RuleEntry) {
+	t.out.Cycles = int(cycle)
+	t.firing = &dto.FiredRule{
+		Cycle:    int(cycle),
+		Rule:     entry.RuleName,
+		Salience: entry.Salience,
+		Matched:  t.matched,
+	}
+	t.before = make(map[string]any, len(t.facts.M))
+	for k, v := range t.facts.M {
+		t.before[k] = v
+	}
+}
+
+// flush appends the rule being fired with the facts it changed
+func (t *tracer) flush() {
+	if t.firing == nil {
+		return
+	}
+
+	t.firing.Changes = diffFacts(t.before, t.facts.M)
+	t.out.Fired = append(t.out.Fired, *t.firing)
+	t.firing, t.before = nil, nil
+}
+
+// diffFacts lists the keys whose value differs between before and after, sorted by key
+func diffFacts(before, after map[string]any) []dto.FactChange {
+
+	changes := make([]dto.FactChange, 0)
+	for k, v := range after {
+		old, ok := before[k]
+		if !ok || !reflect.DeepEqual(old, v) {
+			changes = append(changes, dto.FactChange{Key: k, Before: old, After: v})
+		}
+	}
+	for k, old := range before {
+		if _, ok := after[k]; !ok {
+			changes = append(changes, dto.FactChange{Key: k, Before: old})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Key < changes[j].Key })
+
+	return changes
+}
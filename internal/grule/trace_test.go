@@ -0,0 +1,142 @@
+package grule
+
+import (
+	"context"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/hungpdn/mcp2grule/internal/api/dto"
+	"github.com/hungpdn/mcp2grule/internal/config"
+	"github.com/hungpdn/mcp2grule/internal/storage"
+)
+
+func TestEvaluateTrace(t *testing.T) {
+
+	ctx := context.Background()
+	store := storage.NewMemory()
+	g := New(config.Grule{}, store, store)
+
+	const grl = `
+rule Discount "" salience 10 {
+	when Fact.Get("amount") > 100
+	then Fact.Set("discount", 10); Fact.Set("tier", "gold"); Retract("Discount");
+}
+rule Tax "" salience 5 {
+	when Fact.Get("amount") > 100
+	then Fact.Set("tier", "silver"); Retract("Tax");
+}
+rule Audit "" salience 1 {
+	when true
+	then Fact.Set("audited", true); Retract("Audit");
+}`
+	if _, err := g.Create(ctx, dto.CreateIn{Name: "loan", GRL: grl}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		facts  map[string]any
+		fired  []dto.FiredRule
+		cycles int
+	}{
+		{
+			name:  "rules fire by salience and see the changes of the previous ones",
+			facts: map[string]any{"amount": 150},
+			fired: []dto.FiredRule{
+				{Cycle: 1, Rule: "Discount", Salience: 10, Matched: []string{"Audit", "Discount", "Tax"}, Changes: []dto.FactChange{
+					{Key: "discount", After: int64(10)},
+					{Key: "tier", After: "gold"},
+				}},
+				{Cycle: 2, Rule: "Tax", Salience: 5, Matched: []string{"Audit", "Tax"}, Changes: []dto.FactChange{
+					{Key: "tier", Before: "gold", After: "silver"},
+				}},
+				{Cycle: 3, Rule: "Audit", Salience: 1, Matched: []string{"Audit"}, Changes: []dto.FactChange{
+					{Key: "audited", After: true},
+				}},
+			},
+			cycles: 3,
+		},
+		{
+			name:  "rules whose conditions do not match do not fire",
+			facts: map[string]any{"amount": 50},
+			fired: []dto.FiredRule{
+				{Cycle: 1, Rule: "Audit", Salience: 1, Matched: []string{"Audit"}, Changes: []dto.FactChange{
+					{Key: "audited", After: true},
+				}},
+			},
+			cycles: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := g.Evaluate(ctx, dto.EvaluateIn{RuleName: "loan", Facts: *dto.NewFact(tt.facts), Trace: true})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if out.Trace == nil {
+				t.Fatal("no trace")
+			}
+			if out.Trace.Cycles != tt.cycles {
+				t.Errorf("%d cycles, want %d", out.Trace.Cycles, tt.cycles)
+			}
+			// the rules matched in a cycle are compared regardless of their evaluation order
+			for i := range out.Trace.Fired {
+				slices.Sort(out.Trace.Fired[i].Matched)
+			}
+			if !reflect.DeepEqual(out.Trace.Fired, tt.fired) {
+				t.Errorf("fired rules\n%+v\nwant\n%+v", out.Trace.Fired, tt.fired)
+			}
+		})
+	}
+}
+
+func TestEvaluateWithoutTrace(t *testing.T) {
+
+	ctx := context.Background()
+	store := storage.NewMemory()
+	g := New(config.Grule{}, store, store)
+
+	grl := `rule A "" salience 1 { when true then Fact.Set("done", true); Retract("A"); }`
+	if _, err := g.Create(ctx, dto.CreateIn{Name: "loan", GRL: grl}); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := g.Evaluate(ctx, dto.EvaluateIn{RuleName: "loan", Facts: *dto.NewFact(nil)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Trace != nil {
+		t.Fatalf("trace %+v, want none when not requested", out.Trace)
+	}
+	if out.ModifiedFacts["done"] != true {
+		t.Fatalf("modified facts %v, want done set", out.ModifiedFacts)
+	}
+}
+
+func TestDiffFacts(t *testing.T) {
+
+	tests := []struct {
+		name          string
+		before, after map[string]any
+		want          []dto.FactChange
+	}{
+		{"unchanged", map[string]any{"a": 1}, map[string]any{"a": 1}, []dto.FactChange{}},
+		{"added", map[string]any{}, map[string]any{"a": 1}, []dto.FactChange{{Key: "a", After: 1}}},
+		{"changed", map[string]any{"a": 1}, map[string]any{"a": 2}, []dto.FactChange{{Key: "a", Before: 1, After: 2}}},
+		{"removed", map[string]any{"a": 1}, map[string]any{}, []dto.FactChange{{Key: "a", Before: 1}}},
+		{"nested values compared deeply", map[string]any{"a": []any{1}}, map[string]any{"a": []any{1}}, []dto.FactChange{}},
+		{"sorted by key", map[string]any{"b": 1}, map[string]any{"a": 1, "c": 1}, []dto.FactChange{
+			{Key: "a", After: 1},
+			{Key: "b", Before: 1},
+			{Key: "c", After: 1},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := diffFacts(tt.before, tt.after); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("diffFacts() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
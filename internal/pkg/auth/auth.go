@@ -0,0 +1,81 @@
+package auth
+
+import (
+	"context"
+	"net"
+	"strings"
+	"time"
+
+	sdkauth "github.com/modelcontextprotocol/go-sdk/auth"
+)
+
+// ErrInvalidToken is returned when a bearer token does not authenticate anyone,
+// the HTTP middleware answers it with 401 Unauthorized
+var ErrInvalidToken = sdkauth.ErrInvalidToken
+
+// principalKey is the key of the principal in the extra information of a token
+const principalKey = "principal"
+
+// principalCtxKey is the context key of the principal of a request
+type principalCtxKey struct{}
+
+// Principal is the identity a request is authenticated as
+type Principal struct {
+	Name       string         // Principal of a static token, subject of a JWT
+	Expiration time.Time      // Zero when the token does not expire
+	Claims     map[string]any // Claims of a JWT, nil for static tokens
+}
+
+// Authenticator verifies a bearer token and returns the principal it authenticates
+type Authenticator interface {
+	Authenticate(ctx context.Context, token string) (*Principal, error)
+}
+
+// TokenInfo describes the principal to the MCP SDK, which hands it to the handlers
+// of the requests made with the token
+func (p *Principal) TokenInfo() *sdkauth.TokenInfo {
+
+	// the SDK rejects tokens without expiration, tokens that do not expire are valid for the request
+	expiration := p.Expiration
+	if expiration.IsZero() {
+		expiration = time.Now().Add(time.Hour)
+	}
+
+	return &sdkauth.TokenInfo{
+		Expiration: expiration,
+		Extra:      map[string]any{principalKey: p},
+	}
+}
+
+// FromTokenInfo returns the principal described by the token information, or nil if none
+func FromTokenInfo(info *sdkauth.TokenInfo) *Principal {
+
+	if info == nil {
+		return nil
+	}
+	p, _ := info.Extra[principalKey].(*Principal)
+
+	return p
+}
+
+// WithPrincipal returns a context carrying the principal of a request
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalCtxKey{}, p)
+}
+
+// FromContext returns the principal carried by the context, or nil if none
+func FromContext(ctx context.Context) *Principal {
+	p, _ := ctx.Value(principalCtxKey{}).(*Principal)
+	return p
+}
+
+// IsLoopback reports whether a host to bind only accepts local connections
+func IsLoopback(host string) bool {
+
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(strings.Trim(host, "[]"))
+
+	return ip != nil && ip.IsLoopback()
+}
@@ -0,0 +1,179 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/hungpdn/mcp2grule/internal/pkg/logger"
+)
+
+// minReload is the minimum time between two reloads caused by an unknown key id,
+// so that tokens with made-up key ids cannot hammer the JWKS source
+const minReload = 10 * time.Second
+
+// maxJWKSSize bounds the size of a JWKS document
+const maxJWKSSize = 1 << 20
+
+// JWKS is a JSON Web Key Set read from a file or an http(s) URL. It is cached and read again
+// once stale, or earlier when a token is signed with an unknown key, so that rotated keys are
+// picked up without a restart. When the source fails, the cached keys are kept
+type JWKS struct {
+	source  string
+	refresh time.Duration
+	client  *http.Client
+
+	mu       sync.RWMutex
+	keys     jose.JSONWebKeySet
+	loadedAt time.Time     // Time of the last successful load
+	triedAt  time.Time     // Time of the last load, successful or not
+	loading  chan struct{} // Closed when the load in flight completes, nil when none is
+}
+
+// NewJWKS reads a key set from its source, a file path or an http(s) URL, and reads it again
+// every refresh
+func NewJWKS(ctx context.Context, source string, refresh time.Duration) (*JWKS, error) {
+
+	k := &JWKS{source: source, refresh: refresh, client: &http.Client{Timeout: 10 * time.Second}}
+
+	keys, err := k.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	k.keys, k.loadedAt, k.triedAt = keys, time.Now(), time.Now()
+
+	return k, nil
+}
+
+// Keys returns the public signing keys with a key id, or all of them when kid is empty. A stale key set
+// is read again in the background while the cached keys are used, an unknown key id waits for the key set
+// to be read again as long as ctx allows
+func (k *JWKS) Keys(ctx context.Context, kid string) []jose.JSONWebKey {
+
+	k.mu.RLock()
+	keys := k.find(kid)
+	retry := time.Since(k.triedAt) > minReload || k.loading != nil
+	stale := k.refresh > 0 && time.Since(k.loadedAt) > k.refresh && retry
+	k.mu.RUnlock()
+
+	switch {
+	case len(keys) == 0 && kid != "" && retry:
+		// the key may have been rotated in since the last load
+		select {
+		case <-k.reload(ctx):
+		case <-ctx.Done():
+			return nil
+		}
+		k.mu.RLock()
+		keys = k.find(kid)
+		k.mu.RUnlock()
+	case stale:
+		k.reload(ctx)
+	}
+
+	return keys
+}
+
+// find returns the cached signing keys with a key id, or all of them when kid is empty,
+// callers must hold the lock
+func (k *JWKS) find(kid string) []jose.JSONWebKey {
+
+	var keys []jose.JSONWebKey
+	for _, key := range k.keys.Keys {
+		if (kid == "" || key.KeyID == kid) && (key.Use == "" || key.Use == "sig") {
+			keys = append(keys, key)
+		}
+	}
+
+	return keys
+}
+
+// reload loads the key set again unless a load is in flight, and returns a channel closed once it
+// completes. The load is shared by the callers and runs outside the lock, detached from the context
+// of the request that started it, and the cached keys are kept on failure
+func (k *JWKS) reload(ctx context.Context) <-chan struct{} {
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if k.loading != nil {
+		return k.loading
+	}
+	done := make(chan struct{})
+	k.loading, k.triedAt = done, time.Now()
+
+	go func() {
+		defer close(done)
+
+		ctx := context.WithoutCancel(ctx)
+		keys, err := k.load(ctx)
+
+		k.mu.Lock()
+		defer k.mu.Unlock()
+
+		k.loading = nil
+		if err != nil {
+			logger.WithContext(ctx).Errorf("Failed to reload JWKS from %v, keeping %d cached key(s): %v", k.source, len(k.keys.Keys), err)
+			return
+		}
+		k.keys, k.loadedAt = keys, time.Now()
+	}()
+
+	return done
+}
+
+// load reads and parses the key set
+func (k *JWKS) load(ctx context.Context) (jose.JSONWebKeySet, error) {
+
+	var keys jose.JSONWebKeySet
+
+	data, err := k.read(ctx)
+	if err != nil {
+		return keys, err
+	}
+
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return keys, fmt.Errorf("JWKS %s: %w", k.source, err)
+	}
+	for _, key := range keys.Keys {
+		if !key.IsPublic() {
+			return keys, fmt.Errorf("JWKS %s: key %q is not a public key", k.source, key.KeyID)
+		}
+	}
+	if len(keys.Keys) == 0 {
+		return keys, fmt.Errorf("JWKS %s has no key", k.source)
+	}
+
+	return keys, nil
+}
+
+// read returns the content of the source
+func (k *JWKS) read(ctx context.Context) ([]byte, error) {
+
+	if !strings.HasPrefix(k.source, "http://") && !strings.HasPrefix(k.source, "https://") {
+		return os.ReadFile(k.source)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, k.source, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := k.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("JWKS %s: unexpected status %s", k.source, resp.Status)
+	}
+
+	return io.ReadAll(io.LimitReader(resp.Body, maxJWKSSize))
+}
@@ -0,0 +1,80 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/go-jose/go-jose/v4/jwt"
+)
+
+// signatureAlgorithms are the accepted signature algorithms, all asymmetric
+var signatureAlgorithms = []jose.SignatureAlgorithm{jose.RS256, jose.ES256, jose.EdDSA}
+
+// JWT authenticates JSON Web Tokens signed by a key of a JWKS, issued by the expected issuer
+// for the expected audience. The subject of a token is the name of its principal
+type JWT struct {
+	keys     *JWKS
+	issuer   string
+	audience string
+	leeway   time.Duration
+}
+
+// NewJWT creates an authenticator of the tokens signed by the keys, leeway is the clock skew
+// tolerated when checking the expiry
+func NewJWT(keys *JWKS, issuer, audience string, leeway time.Duration) *JWT {
+	return &JWT{keys: keys, issuer: issuer, audience: audience, leeway: leeway}
+}
+
+// Authenticate verifies the signature and the claims of a token and returns its principal
+func (j *JWT) Authenticate(ctx context.Context, token string) (*Principal, error) {
+
+	p, err := j.verify(ctx, token)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	return p, nil
+}
+
+// verify checks a token, its errors are not wrapped into ErrInvalidToken yet
+func (j *JWT) verify(ctx context.Context, token string) (*Principal, error) {
+
+	tok, err := jwt.ParseSigned(token, signatureAlgorithms)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		claims jwt.Claims
+		extra  map[string]any
+	)
+	verified := false
+	for _, key := range j.keys.Keys(ctx, tok.Headers[0].KeyID) {
+		if key.Algorithm != "" && key.Algorithm != tok.Headers[0].Algorithm {
+			continue
+		}
+		if err := tok.Claims(key.Key, &claims, &extra); err == nil {
+			verified = true
+			break
+		}
+	}
+	if !verified {
+		return nil, errors.New("signature does not match any key of the JWKS")
+	}
+
+	expected := jwt.Expected{Issuer: j.issuer, AnyAudience: jwt.Audience{j.audience}, Time: time.Now()}
+	if err := claims.ValidateWithLeeway(expected, j.leeway); err != nil {
+		return nil, err
+	}
+	if claims.Expiry == nil {
+		return nil, errors.New("token without expiry")
+	}
+	if claims.Subject == "" {
+		return nil, errors.New("token without subject")
+	}
+
+	return &Principal{Name: claims.Subject, Expiration: claims.Expiry.Time(), Claims: extra}, nil
+}
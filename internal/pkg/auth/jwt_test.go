@@ -0,0 +1,125 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/go-jose/go-jose/v4/jwt"
+)
+
+const (
+	testIssuer   = "https://issuer.example"
+	testAudience = "mcp2grule"
+)
+
+// newTestJWT returns an authenticator trusting the public key of signer under the key id "k1"
+func newTestJWT(t *testing.T, signer *ecdsa.PrivateKey) *JWT {
+	t.Helper()
+
+	set := jose.JSONWebKeySet{Keys: []jose.JSONWebKey{
+		{Key: &signer.PublicKey, KeyID: "k1", Algorithm: string(jose.ES256), Use: "sig"},
+	}}
+	data, err := json.Marshal(set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	keys, err := NewJWKS(context.Background(), path, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return NewJWT(keys, testIssuer, testAudience, 30*time.Second)
+}
+
+// sign returns a token of the claims signed with alg by key under a key id
+func sign(t *testing.T, alg jose.SignatureAlgorithm, key any, kid string, claims map[string]any) string {
+	t.Helper()
+
+	opts := (&jose.SignerOptions{}).WithType("JWT").WithHeader("kid", kid)
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: alg, Key: key}, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := jwt.Signed(signer).Claims(claims).Serialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return token
+}
+
+func TestJWTAuthenticate(t *testing.T) {
+
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	other, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	j := newTestJWT(t, key)
+
+	now := time.Now()
+	claims := func(edit func(map[string]any)) map[string]any {
+		c := map[string]any{
+			"iss":   testIssuer,
+			"aud":   []string{testAudience, "other"},
+			"sub":   "alice",
+			"exp":   now.Add(time.Hour).Unix(),
+			"iat":   now.Unix(),
+			"roles": []string{"author"},
+		}
+		if edit != nil {
+			edit(c)
+		}
+		return c
+	}
+
+	tests := []struct {
+		name  string
+		token string
+		valid bool
+	}{
+		{"valid", sign(t, jose.ES256, key, "k1", claims(nil)), true},
+		{"expired within leeway", sign(t, jose.ES256, key, "k1", claims(func(c map[string]any) { c["exp"] = now.Add(-10 * time.Second).Unix() })), true},
+		{"expired", sign(t, jose.ES256, key, "k1", claims(func(c map[string]any) { c["exp"] = now.Add(-time.Hour).Unix() })), false},
+		{"without expiry", sign(t, jose.ES256, key, "k1", claims(func(c map[string]any) { delete(c, "exp") })), false},
+		{"not yet valid", sign(t, jose.ES256, key, "k1", claims(func(c map[string]any) { c["nbf"] = now.Add(time.Hour).Unix() })), false},
+		{"wrong issuer", sign(t, jose.ES256, key, "k1", claims(func(c map[string]any) { c["iss"] = "https://evil.example" })), false},
+		{"wrong audience", sign(t, jose.ES256, key, "k1", claims(func(c map[string]any) { c["aud"] = "other" })), false},
+		{"without subject", sign(t, jose.ES256, key, "k1", claims(func(c map[string]any) { delete(c, "sub") })), false},
+		{"signed by another key", sign(t, jose.ES256, other, "k1", claims(nil)), false},
+		{"unknown key id", sign(t, jose.ES256, key, "k2", claims(nil)), false},
+		{"symmetric algorithm", sign(t, jose.HS256, []byte("0123456789abcdef0123456789abcdef"), "k1", claims(nil)), false},
+		{"not a JWT", "not.a.jwt", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := j.Authenticate(context.Background(), tt.token)
+			if !tt.valid {
+				if !errors.Is(err, ErrInvalidToken) {
+					t.Fatalf("Authenticate() = %v, %v, want ErrInvalidToken", p, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Authenticate() = %v, want a principal", err)
+			}
+			if p.Name != "alice" || p.Expiration.IsZero() {
+				t.Fatalf("principal = %+v, want alice with an expiration", p)
+			}
+			if _, ok := p.Claims["roles"]; !ok {
+				t.Fatalf("claims = %v, want the roles claim", p.Claims)
+			}
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package auth
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// token is a static bearer token, stored as its hash so that
+// every comparison takes the same time whatever the token length
+type token struct {
+	principal string
+	hash      [sha256.Size]byte
+}
+
+// Tokens authenticates static bearer tokens, each one naming its principal
+type Tokens struct {
+	tokens []token
+}
+
+// NewTokens creates an empty set of tokens
+func NewTokens() *Tokens {
+	return &Tokens{}
+}
+
+// Add adds the token of a principal
+func (t *Tokens) Add(principal, value string) error {
+
+	if principal == "" || value == "" {
+		return fmt.Errorf("token of principal %q: principal and token are required", principal)
+	}
+	for _, tok := range t.tokens {
+		if tok.principal == principal {
+			return fmt.Errorf("principal %q has several tokens", principal)
+		}
+	}
+
+	t.tokens = append(t.tokens, token{principal: principal, hash: sha256.Sum256([]byte(value))})
+
+	return nil
+}
+
+// Load adds the tokens of a file with one principal:token per line,
+// blank lines and lines starting with # are skipped
+func (t *Tokens) Load(path string) error {
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		principal, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return fmt.Errorf("%s:%d: expected principal:token", path, n)
+		}
+		if err := t.Add(strings.TrimSpace(principal), strings.TrimSpace(value)); err != nil {
+			return fmt.Errorf("%s:%d: %w", path, n, err)
+		}
+	}
+
+	return scanner.Err()
+}
+
+// Len returns the number of tokens
+func (t *Tokens) Len() int {
+	return len(t.tokens)
+}
+
+// Contains reports whether a token is one of the tokens, in constant time
+func (t *Tokens) Contains(value string) bool {
+	_, ok := t.lookup(value)
+	return ok
+}
+
+// Authenticate returns the principal of a token
+func (t *Tokens) Authenticate(_ context.Context, value string) (*Principal, error) {
+
+	principal, ok := t.lookup(value)
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+
+	return &Principal{Name: principal}, nil
+}
+
+// lookup compares a token with all the tokens without stopping at the first match,
+// so that the time taken does not tell which token matched or how much of it
+func (t *Tokens) lookup(value string) (string, bool) {
+
+	hash := sha256.Sum256([]byte(value))
+
+	principal, found := "", 0
+	for _, tok := range t.tokens {
+		if subtle.ConstantTimeCompare(hash[:], tok.hash[:]) == 1 {
+			principal, found = tok.principal, 1
+		}
+	}
+
+	return principal, found == 1
+}
@@ -0,0 +1,18 @@
+package authz
+
+import "context"
+
+// serverCtxKey is the context key marking the calls the server makes on its own behalf
+type serverCtxKey struct{}
+
+// AsServer returns a context for the calls the server makes on its own behalf rather than
+// for a client, such as listing the rulesets to register, they are not restricted
+func AsServer(ctx context.Context) context.Context {
+	return context.WithValue(ctx, serverCtxKey{}, true)
+}
+
+// IsServer reports whether a context is one of a call made by the server on its own behalf
+func IsServer(ctx context.Context) bool {
+	server, _ := ctx.Value(serverCtxKey{}).(bool)
+	return server
+}
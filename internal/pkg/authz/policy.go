@@ -0,0 +1,183 @@
+package authz
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/hungpdn/mcp2grule/internal/pkg/auth"
+)
+
+// ErrForbidden is returned when a subject is not allowed an operation
+var ErrForbidden = errors.New("forbidden")
+
+// Subject is a principal with its effective role and groups
+type Subject struct {
+	Name   string
+	Role   Role
+	Groups []string
+}
+
+// Grant is what the policy file gives a principal
+type Grant struct {
+	Role   Role     `json:"role"`
+	Groups []string `json:"groups,omitempty"`
+}
+
+// ACL restricts the rulesets whose name starts with Prefix to the principals and groups listed.
+// An empty list does not restrict its operations, editors may also read
+type ACL struct {
+	Prefix string   `json:"prefix"`
+	Edit   []string `json:"edit,omitempty"` // May create, update, roll back and delete the rulesets
+	Read   []string `json:"read,omitempty"` // May read and evaluate the rulesets
+}
+
+// Policy maps principals to roles and groups and holds the ruleset ACLs
+type Policy struct {
+	defaultRole Role
+	rolesClaim  string
+	groupsClaim string
+	principals  map[string]Grant
+	acls        []ACL // Longest prefix first
+}
+
+// policyFile is the JSON document read by Load
+type policyFile struct {
+	Principals map[string]Grant `json:"principals"`
+	Rulesets   []ACL            `json:"rulesets"`
+}
+
+// NewPolicy creates a policy without grants nor ACLs. Principals get the highest of their granted role,
+// the roles named by their rolesClaim JWT claim and the default role. Their groups are the granted ones
+// and those named by their groupsClaim JWT claim
+func NewPolicy(defaultRole Role, rolesClaim, groupsClaim string) *Policy {
+	return &Policy{
+		defaultRole: defaultRole,
+		rolesClaim:  rolesClaim,
+		groupsClaim: groupsClaim,
+		principals:  map[string]Grant{},
+	}
+}
+
+// Load adds the grants and ACLs of a JSON policy file
+func (p *Policy) Load(path string) error {
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var file policyFile
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&file); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	for name, grant := range file.Principals {
+		p.principals[name] = grant
+	}
+	for _, acl := range file.Rulesets {
+		if acl.Prefix == "" {
+			return fmt.Errorf("%s: ruleset ACL without prefix", path)
+		}
+		p.acls = append(p.acls, acl)
+	}
+	sort.SliceStable(p.acls, func(i, j int) bool { return len(p.acls[i].Prefix) > len(p.acls[j].Prefix) })
+
+	return nil
+}
+
+// Subject returns the effective role and groups of a principal
+func (p *Policy) Subject(principal *auth.Principal) Subject {
+
+	grant := p.principals[principal.Name]
+	s := Subject{Name: principal.Name, Role: max(grant.Role, p.defaultRole), Groups: slices.Clone(grant.Groups)}
+
+	for _, name := range claimValues(principal.Claims, p.rolesClaim) {
+		if role, err := ParseRole(name); err == nil {
+			s.Role = max(s.Role, role)
+		}
+	}
+	s.Groups = append(s.Groups, claimValues(principal.Claims, p.groupsClaim)...)
+
+	return s
+}
+
+// Authorize checks that a subject may run an operation on a ruleset, or on no ruleset in particular
+// when ruleset is empty
+func (p *Policy) Authorize(s Subject, op Operation, ruleset string) error {
+
+	if s.Role < required[op] {
+		return fmt.Errorf("%w: %s requires role %s, %q has role %s", ErrForbidden, op, required[op], s.Name, s.Role)
+	}
+	if ruleset == "" || s.Role == RoleAdmin {
+		return nil
+	}
+
+	acl, ok := p.acl(ruleset)
+	if !ok {
+		return nil
+	}
+	allowed := acl.Edit
+	if !op.edits() {
+		if len(acl.Read) == 0 {
+			return nil
+		}
+		allowed = append(slices.Clone(acl.Read), acl.Edit...)
+	}
+	if len(allowed) == 0 || s.matches(allowed) {
+		return nil
+	}
+
+	return fmt.Errorf("%w: %s on rulesets %s* is restricted to %s, %q is not one of them",
+		ErrForbidden, op, acl.Prefix, strings.Join(allowed, ", "), s.Name)
+}
+
+// acl returns the ACL with the longest prefix of a ruleset name
+func (p *Policy) acl(ruleset string) (ACL, bool) {
+	for _, acl := range p.acls {
+		if strings.HasPrefix(ruleset, acl.Prefix) {
+			return acl, true
+		}
+	}
+	return ACL{}, false
+}
+
+// matches reports whether the subject is named in a list, by its name or one of its groups
+func (s Subject) matches(names []string) bool {
+	for _, name := range names {
+		if name == s.Name || slices.Contains(s.Groups, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// claimValues returns a claim holding a string or a list of strings
+func claimValues(claims map[string]any, name string) []string {
+
+	if name == "" {
+		return nil
+	}
+
+	switch v := claims[name].(type) {
+	case string:
+		return strings.Fields(v)
+	case []any:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,125 @@
+package authz
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hungpdn/mcp2grule/internal/pkg/auth"
+)
+
+const testPolicy = `{
+	"principals": {
+		"alice": {"role": "admin"},
+		"bob": {"role": "author", "groups": ["pricing"]},
+		"carol": {"role": "evaluator"},
+		"dave": {"role": "author"}
+	},
+	"rulesets": [
+		{"prefix": "pricing.", "edit": ["pricing"]},
+		{"prefix": "pricing.secret.", "edit": ["dave"], "read": ["carol"]},
+		{"prefix": "hr.", "read": ["nobody"]}
+	]
+}`
+
+func loadPolicy(t *testing.T) *Policy {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "policy.json")
+	if err := os.WriteFile(path, []byte(testPolicy), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	p := NewPolicy(RoleViewer, "roles", "groups")
+	if err := p.Load(path); err != nil {
+		t.Fatal(err)
+	}
+
+	return p
+}
+
+func TestPolicyAuthorize(t *testing.T) {
+
+	p := loadPolicy(t)
+
+	tests := []struct {
+		name      string
+		principal string
+		op        Operation
+		ruleset   string
+		allowed   bool
+	}{
+		{"admin bypasses the ACLs", "alice", OpDelete, "pricing.secret.loan", true},
+		{"role too low", "carol", OpWrite, "other", false},
+		{"no ACL matches", "dave", OpWrite, "other", true},
+		{"no ruleset in particular", "carol", OpEvaluate, "", true},
+		{"editor by group", "bob", OpWrite, "pricing.loan", true},
+		{"not an editor", "dave", OpWrite, "pricing.loan", false},
+		{"read not restricted", "carol", OpEvaluate, "pricing.loan", true},
+		{"longest prefix wins for edits", "bob", OpWrite, "pricing.secret.loan", false},
+		{"editor of the longest prefix", "dave", OpWrite, "pricing.secret.loan", true},
+		{"reader of the longest prefix", "carol", OpRead, "pricing.secret.loan", true},
+		{"editors may read", "dave", OpEvaluate, "pricing.secret.loan", true},
+		{"not a reader of the longest prefix", "bob", OpRead, "pricing.secret.loan", false},
+		{"read restricted", "carol", OpRead, "hr.payroll", false},
+		{"default role", "eve", OpRead, "pricing.loan", true},
+		{"default role too low", "eve", OpEvaluate, "pricing.loan", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := p.Subject(&auth.Principal{Name: tt.principal})
+			err := p.Authorize(s, tt.op, tt.ruleset)
+			if tt.allowed && err != nil {
+				t.Fatalf("Authorize(%s, %s, %q) = %v, want allowed", tt.principal, tt.op, tt.ruleset, err)
+			}
+			if !tt.allowed && !errors.Is(err, ErrForbidden) {
+				t.Fatalf("Authorize(%s, %s, %q) = %v, want ErrForbidden", tt.principal, tt.op, tt.ruleset, err)
+			}
+		})
+	}
+}
+
+func TestPolicySubject(t *testing.T) {
+
+	p := loadPolicy(t)
+
+	tests := []struct {
+		name      string
+		principal auth.Principal
+		role      Role
+		groups    []string
+	}{
+		{"granted", auth.Principal{Name: "bob"}, RoleAuthor, []string{"pricing"}},
+		{"default", auth.Principal{Name: "eve"}, RoleViewer, nil},
+		{"claims raise the role", auth.Principal{Name: "carol", Claims: map[string]any{"roles": []any{"author", "unknown"}}}, RoleAuthor, nil},
+		{"claims do not lower the role", auth.Principal{Name: "alice", Claims: map[string]any{"roles": "viewer"}}, RoleAdmin, nil},
+		{"claim groups are added", auth.Principal{Name: "bob", Claims: map[string]any{"groups": []any{"hr"}}}, RoleAuthor, []string{"pricing", "hr"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := p.Subject(&tt.principal)
+			if s.Role != tt.role {
+				t.Errorf("role = %s, want %s", s.Role, tt.role)
+			}
+			if len(s.Groups) != len(tt.groups) {
+				t.Fatalf("groups = %v, want %v", s.Groups, tt.groups)
+			}
+			for i := range tt.groups {
+				if s.Groups[i] != tt.groups[i] {
+					t.Fatalf("groups = %v, want %v", s.Groups, tt.groups)
+				}
+			}
+		})
+	}
+}
+
+func TestPolicyLoadRejectsUnknownFields(t *testing.T) {
+
+	path := filepath.Join(t.TempDir(), "policy.json")
+	if err := os.WriteFile(path, []byte(`{"principals": {}, "acls": []}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := NewPolicy(RoleNone, "", "").Load(path); err == nil {
+		t.Fatal("Load accepted an unknown field")
+	}
+}
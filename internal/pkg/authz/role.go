@@ -0,0 +1,83 @@
+package authz
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Role grants the operations of its level and of every level below it
+type Role int
+
+const (
+	RoleNone      Role = iota // Grants nothing
+	RoleViewer                // Lists and reads rulesets and pipelines
+	RoleEvaluator             // Also evaluates rulesets and pipelines and tries GRL
+	RoleAuthor                // Also creates, updates and rolls back rulesets and pipelines
+	RoleAdmin                 // Also deletes rulesets and pipelines, and is not bound by the ruleset ACLs
+)
+
+var roleNames = map[Role]string{
+	RoleNone:      "none",
+	RoleViewer:    "viewer",
+	RoleEvaluator: "evaluator",
+	RoleAuthor:    "author",
+	RoleAdmin:     "admin",
+}
+
+func (r Role) String() string {
+	if name, ok := roleNames[r]; ok {
+		return name
+	}
+	return fmt.Sprintf("Role(%d)", int(r))
+}
+
+// ParseRole returns the role of a name, case insensitive
+func ParseRole(name string) (Role, error) {
+	for role, n := range roleNames {
+		if strings.EqualFold(name, n) {
+			return role, nil
+		}
+	}
+	return RoleNone, fmt.Errorf("unknown role %q, expected none, viewer, evaluator, author or admin", name)
+}
+
+// UnmarshalJSON reads a role from its name
+func (r *Role) UnmarshalJSON(data []byte) error {
+
+	var name string
+	if err := json.Unmarshal(data, &name); err != nil {
+		return err
+	}
+
+	role, err := ParseRole(name)
+	if err != nil {
+		return err
+	}
+	*r = role
+
+	return nil
+}
+
+// Operation is a kind of call to the grule service
+type Operation string
+
+const (
+	OpRead     Operation = "read"     // grule.list, grule.detail, grule.schema, grule.history and the pipeline reads
+	OpEvaluate Operation = "evaluate" // grule.evaluate*, grule.try and the ruleset tools
+	OpWrite    Operation = "write"    // grule.create, grule.update, grule.rollback and the pipeline writes
+	OpDelete   Operation = "delete"   // grule.delete and grule.delete_pipeline
+)
+
+// required is the lowest role granting an operation
+var required = map[Operation]Role{
+	OpRead:     RoleViewer,
+	OpEvaluate: RoleEvaluator,
+	OpWrite:    RoleAuthor,
+	OpDelete:   RoleAdmin,
+}
+
+// edits reports whether an operation changes a ruleset, as opposed to reading it
+func (o Operation) edits() bool {
+	return o == OpWrite || o == OpDelete
+}
@@ -0,0 +1,117 @@
+package metrics
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// namespace prefixes the name of every metric
+const namespace = "mcp2grule"
+
+// Registry holds the metrics of the server, together with the Go runtime and process ones
+var Registry = prometheus.NewRegistry()
+
+var factory = promauto.With(Registry)
+
+var (
+	ToolCalls = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "tool_calls_total",
+		Help:      "MCP tool calls, by tool.",
+	}, []string{"tool"})
+
+	ToolErrors = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "tool_errors_total",
+		Help:      "MCP tool calls that failed, by tool and error code.",
+	}, []string{"tool", "code"})
+
+	ToolDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "tool_duration_seconds",
+		Help:      "Duration of the MCP tool calls, by tool.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"tool"})
+
+	EvaluateDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "evaluate_duration_seconds",
+		Help:      "Duration of the evaluations of a ruleset, by ruleset.",
+		Buckets:   []float64{.0001, .00025, .0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+	}, []string{"ruleset"})
+
+	CacheHits = factory.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "engine_cache_hits_total",
+		Help:      "Evaluations that found their ruleset compiled in the engine cache.",
+	})
+
+	CacheMisses = factory.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "engine_cache_misses_total",
+		Help:      "Evaluations that had to compile their ruleset into the engine cache.",
+	})
+
+	CacheEvictions = factory.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "engine_cache_evictions_total",
+		Help:      "Rulesets found evicted or expired from the engine cache when next evaluated.",
+	})
+
+	Rulesets = factory.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "rulesets",
+		Help:      "Stored rulesets.",
+	})
+
+	StorageDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "storage_operation_duration_seconds",
+		Help:      "Duration of the storage operations, by operation and outcome.",
+		Buckets:   []float64{.0001, .0005, .001, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"operation", "outcome"})
+)
+
+func init() {
+	Registry.MustRegister(collectors.NewGoCollector(), collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+}
+
+// Handler serves the metrics in the Prometheus exposition format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
+
+// Sessions exposes the number of open MCP sessions of a transport, counted when scraped
+func Sessions(transport string, count func() int) error {
+
+	err := Registry.Register(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace:   namespace,
+		Name:        "sessions",
+		Help:        "Open MCP sessions, by transport.",
+		ConstLabels: prometheus.Labels{"transport": transport},
+	}, func() float64 { return float64(count()) }))
+
+	var registered prometheus.AlreadyRegisteredError
+	if errors.As(err, &registered) {
+		return nil
+	}
+
+	return err
+}
+
+// ObserveStorage records the duration of a storage operation started at start
+func ObserveStorage(operation string, start time.Time, err error) {
+
+	outcome := "ok"
+	if err != nil {
+		outcome = "error"
+	}
+
+	StorageDuration.WithLabelValues(operation, outcome).Observe(time.Since(start).Seconds())
+}
@@ -0,0 +1,126 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"sync"
+
+	"github.com/hungpdn/mcp2grule/internal/pkg/auth"
+	"github.com/hungpdn/mcp2grule/internal/pkg/logger"
+	sdkauth "github.com/modelcontextprotocol/go-sdk/auth"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// Auth requires an Authorization: Bearer header authenticated by a, requests without
+// a valid token get 401 Unauthorized. The principal is put in the context of the request
+// and handed to the MCP handlers through the token information of the request
+func Auth(next http.Handler, a auth.Authenticator) http.Handler {
+
+	verify := func(ctx context.Context, token string) (*sdkauth.TokenInfo, error) {
+		p, err := a.Authenticate(ctx, token)
+		if err != nil {
+			logger.WithContext(ctx).Warnf("Rejected bearer token: %v", err)
+			return nil, err
+		}
+		return p.TokenInfo(), nil
+	}
+
+	withPrincipal := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p := auth.FromTokenInfo(sdkauth.TokenInfoFromContext(r.Context()))
+		next.ServeHTTP(w, r.WithContext(auth.WithPrincipal(r.Context(), p)))
+	})
+
+	return sdkauth.RequireBearerToken(verify, nil)(withPrincipal)
+}
+
+// Principal puts the principal of each MCP request, given by the SDK in its token information,
+// in the context of its handlers. They otherwise get the context of the HTTP request that opened
+// the session, and so the principal of its token whoever makes the later requests. Requests
+// without token information, over sse and stdio, keep the principal of their context
+func Principal(next mcp.MethodHandler) mcp.MethodHandler {
+	return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+
+		if extra := req.GetExtra(); extra != nil {
+			if p := auth.FromTokenInfo(extra.TokenInfo); p != nil {
+				ctx = auth.WithPrincipal(ctx, p)
+			}
+		}
+
+		return next(ctx, method, req)
+	}
+}
+
+// SSESessions binds every sse session to the principal of the GET request that opened it, POSTs to
+// the session by another principal get 403 Forbidden. The sse transport does not hand the token
+// information of a POST to the MCP handlers, which run with the principal of the session instead.
+// It reads the principal from the request context so it must be wrapped by Auth
+func SSESessions(next http.Handler) http.Handler {
+
+	var mu sync.Mutex
+	owners := map[string]string{} // session ID to principal name
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		name := ""
+		if p := auth.FromContext(r.Context()); p != nil {
+			name = p.Name
+		}
+
+		if r.Method == http.MethodPost {
+			mu.Lock()
+			owner, ok := owners[r.URL.Query().Get("sessionid")]
+			mu.Unlock()
+			if ok && owner != name {
+				logger.WithContext(r.Context()).Warnf("Rejected POST of %q to an sse session of %q", name, owner)
+				http.Error(w, "session belongs to another principal", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		sw := &sessionWriter{ResponseWriter: w, opened: func(id string) {
+			mu.Lock()
+			owners[id] = name
+			mu.Unlock()
+		}}
+		defer func() {
+			if sw.id != "" {
+				mu.Lock()
+				delete(owners, sw.id)
+				mu.Unlock()
+			}
+		}()
+
+		next.ServeHTTP(sw, r)
+	})
+}
+
+// sessionWriter reads the session ID from the endpoint event, the first one of an sse stream
+type sessionWriter struct {
+	http.ResponseWriter
+	opened func(id string)
+	id     string
+	seen   bool
+}
+
+func (w *sessionWriter) Write(b []byte) (int, error) {
+
+	if !w.seen {
+		w.seen = true
+		if _, rest, ok := bytes.Cut(b, []byte("sessionid=")); ok {
+			id, _, _ := bytes.Cut(rest, []byte("\n"))
+			w.id = string(bytes.TrimSpace(id))
+			w.opened(w.id)
+		}
+	}
+
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *sessionWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
@@ -1 +1,156 @@
 package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/hungpdn/mcp2grule/internal/pkg/auth"
+	"github.com/hungpdn/mcp2grule/internal/pkg/logger"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// RequestIDHeader is the HTTP header carrying the correlation ID of a request
+const RequestIDHeader = "X-Request-ID"
+
+// correlationIDMeta is the key of the correlation ID in the metadata of a tool result
+const correlationIDMeta = "correlation_id"
+
+// requestIDValid matches the incoming request IDs that are kept, others are replaced
+// so that a client cannot inject anything into the logs
+var requestIDValid = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// RequestID gives every HTTP request a correlation ID, the X-Request-ID header of the request
+// when valid or a new one. The ID is put in the request context and headers, where the MCP
+// handlers of streamable-http find it, and returned in the X-Request-ID header of the response
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		id := r.Header.Get(RequestIDHeader)
+		if !requestIDValid.MatchString(id) {
+			id = logger.NewCorrelationID()
+			r.Header.Set(RequestIDHeader, id)
+		}
+		w.Header().Set(RequestIDHeader, id)
+
+		ctx := context.WithValue(r.Context(), logger.CorrelationIdCtxKey, id)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// toolArguments are the arguments of the tools naming a ruleset or a pipeline
+type toolArguments struct {
+	Name      string   `json:"name"`
+	RuleName  string   `json:"rule_name"`
+	RuleNames []string `json:"rule_names"`
+	Tag       string   `json:"tag"`
+	Pipeline  string   `json:"pipeline"`
+}
+
+// Logging gives every MCP tool call a correlation ID, logs the call with its outcome and duration,
+// and returns the ID in the correlation_id metadata of the result. The ID is the X-Request-ID header
+// of the HTTP request on streamable-http, set by RequestID, and a new one over sse and stdio whose
+// calls do not carry the headers of their HTTP request
+func Logging(next mcp.MethodHandler) mcp.MethodHandler {
+	return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+
+		call, ok := req.(*mcp.CallToolRequest)
+		if !ok {
+			return next(ctx, method, req)
+		}
+
+		// the context of an sse call is the one of its session, whose ID is not the call's
+		ctx = context.WithValue(ctx, logger.CorrelationIdCtxKey, "")
+		if extra := req.GetExtra(); extra != nil && extra.Header != nil {
+			if id := extra.Header.Get(RequestIDHeader); requestIDValid.MatchString(id) {
+				ctx = context.WithValue(ctx, logger.CorrelationIdCtxKey, id)
+			}
+		}
+		ctx = logger.SetCorrelationIdToCtx(ctx)
+
+		start := time.Now()
+		res, err := next(ctx, method, req)
+
+		attrs := logger.Attrs{
+			"tool":        call.Params.Name,
+			"duration_ms": float64(time.Since(start).Microseconds()) / 1000,
+		}
+		for key, value := range callAttrs(ctx, call) {
+			attrs[key] = value
+		}
+		log := logger.WithContext(ctx)
+
+		result, _ := res.(*mcp.CallToolResult)
+		switch {
+		case err != nil || result == nil:
+			attrs["outcome"] = "protocol_error"
+			log.WithAttrs(attrs).Warnf("Tool call %s rejected: %v", call.Params.Name, err)
+		case result.IsError:
+			attrs["outcome"] = fmt.Sprint(result.Meta["error_code"])
+			log.WithAttrs(attrs).Warnf("Tool call %s failed", call.Params.Name)
+		default:
+			attrs["outcome"] = "ok"
+			log.WithAttrs(attrs).Infof("Tool call %s succeeded", call.Params.Name)
+		}
+
+		if result != nil {
+			if result.Meta == nil {
+				result.Meta = mcp.Meta{}
+			}
+			result.Meta[correlationIDMeta] = logger.GetCorrelationIdFromCtx(ctx)
+		}
+
+		return res, err
+	}
+}
+
+// callAttrs returns the principal of a tool call and the ruleset or pipeline it names, if any
+func callAttrs(ctx context.Context, call *mcp.CallToolRequest) logger.Attrs {
+
+	attrs := logger.Attrs{}
+
+	// the token of the call names the caller, the context the one who opened the session over sse
+	var p *auth.Principal
+	if call.Extra != nil {
+		p = auth.FromTokenInfo(call.Extra.TokenInfo)
+	}
+	if p == nil {
+		p = auth.FromContext(ctx)
+	}
+	if p != nil {
+		attrs["principal"] = p.Name
+	}
+
+	// the arguments are the raw JSON of the request on the server side
+	data, err := json.Marshal(call.Params.Arguments)
+	if err != nil {
+		return attrs
+	}
+	var args toolArguments
+	if err := json.Unmarshal(data, &args); err != nil {
+		return attrs
+	}
+
+	switch {
+	case args.Pipeline != "":
+		attrs["pipeline"] = args.Pipeline
+	case strings.Contains(call.Params.Name, "pipeline"):
+		if args.Name != "" {
+			attrs["pipeline"] = args.Name
+		}
+	case args.RuleName != "":
+		attrs["ruleset"] = args.RuleName
+	case len(args.RuleNames) > 0:
+		attrs["ruleset"] = strings.Join(args.RuleNames, ",")
+	case args.Tag != "":
+		attrs["tag"] = args.Tag
+	case args.Name != "":
+		attrs["ruleset"] = args.Name
+	}
+
+	return attrs
+}
@@ -0,0 +1,90 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/hungpdn/mcp2grule/internal/pkg/logger"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// requestIDTransport sets the X-Request-ID header of the requests of an MCP client
+// and records the one of the response to its last POST
+type requestIDTransport struct {
+	id       string
+	mu       sync.Mutex
+	returned string
+}
+
+func (t *requestIDTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+
+	r = r.Clone(r.Context())
+	if t.id != "" {
+		r.Header.Set(RequestIDHeader, t.id)
+	}
+	resp, err := http.DefaultTransport.RoundTrip(r)
+	if err == nil && r.Method == http.MethodPost {
+		t.mu.Lock()
+		t.returned = resp.Header.Get(RequestIDHeader)
+		t.mu.Unlock()
+	}
+	return resp, err
+}
+
+func TestCorrelationID(t *testing.T) {
+
+	ctx := context.Background()
+
+	// the correlation ID seen by the tool handler
+	var handled string
+	server := mcp.NewServer(&mcp.Implementation{Name: "test"}, nil)
+	server.AddReceivingMiddleware(Logging)
+	mcp.AddTool(server, &mcp.Tool{Name: "echo"}, func(ctx context.Context, _ *mcp.CallToolRequest, _ struct{}) (*mcp.CallToolResult, any, error) {
+		handled = logger.GetCorrelationIdFromCtx(ctx)
+		return &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: "ok"}}}, nil, nil
+	})
+	ts := httptest.NewServer(RequestID(mcp.NewStreamableHTTPHandler(func(*http.Request) *mcp.Server { return server }, nil)))
+	defer ts.Close()
+
+	tests := []struct {
+		name string
+		id   string // X-Request-ID of the requests, empty when not set
+		kept bool   // whether the ID is returned as is
+	}{
+		{"valid ID is kept", "req-42:retry.1", true},
+		{"missing ID is generated", "", false},
+		{"invalid ID is replaced", "id with {spaces}", false},
+		{"too long ID is replaced", strings.Repeat("a", 129), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rt := &requestIDTransport{id: tt.id}
+			client := mcp.NewClient(&mcp.Implementation{Name: "test"}, nil)
+			session, err := client.Connect(ctx, &mcp.StreamableClientTransport{Endpoint: ts.URL, HTTPClient: &http.Client{Transport: rt}}, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer session.Close()
+
+			res, err := session.CallTool(ctx, &mcp.CallToolParams{Name: "echo", Arguments: map[string]any{}})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, _ := res.Meta[correlationIDMeta].(string)
+			rt.mu.Lock()
+			returned := rt.returned
+			rt.mu.Unlock()
+			if got == "" || got != returned || got != handled {
+				t.Fatalf("correlation_id %q, X-Request-ID %q and handler ID %q, want the same ID", got, returned, handled)
+			}
+			if (got == tt.id) != tt.kept {
+				t.Fatalf("correlation_id %q for the request ID %q, kept %v", got, tt.id, tt.kept)
+			}
+		})
+	}
+}
@@ -1 +1,48 @@
 package middleware
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hungpdn/mcp2grule/internal/pkg/metrics"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// protocolErrorTool labels the tool calls rejected before reaching a tool, e.g. unknown tools
+// or invalid arguments, so that made-up tool names do not add series
+const protocolErrorTool = "-"
+
+// Metrics counts the MCP tool calls, their errors by code and their duration, by tool
+func Metrics(next mcp.MethodHandler) mcp.MethodHandler {
+	return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+
+		call, ok := req.(*mcp.CallToolRequest)
+		if !ok {
+			return next(ctx, method, req)
+		}
+
+		start := time.Now()
+		res, err := next(ctx, method, req)
+
+		tool, code := call.Params.Name, ""
+		result, _ := res.(*mcp.CallToolResult)
+		switch {
+		case err != nil || result == nil:
+			tool, code = protocolErrorTool, "protocol_error"
+		case result.IsError:
+			code = "unknown"
+			if c, ok := result.Meta["error_code"]; ok {
+				code = fmt.Sprint(c)
+			}
+		}
+
+		metrics.ToolCalls.WithLabelValues(tool).Inc()
+		metrics.ToolDuration.WithLabelValues(tool).Observe(time.Since(start).Seconds())
+		if code != "" {
+			metrics.ToolErrors.WithLabelValues(tool, code).Inc()
+		}
+
+		return res, err
+	}
+}
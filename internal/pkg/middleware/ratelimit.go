@@ -1 +1,218 @@
 package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/hungpdn/mcp2grule/internal/pkg/auth"
+	"github.com/hungpdn/mcp2grule/internal/pkg/logger"
+	"golang.org/x/time/rate"
+)
+
+// idleBucket is how long the bucket of a client is kept after its last request
+const idleBucket = 10 * time.Minute
+
+// maxBodySize bounds the body of the requests read to find their budgets
+const maxBodySize = 10 << 20
+
+// Budget is a class of requests sharing a rate limit
+type Budget string
+
+const (
+	BudgetRead     Budget = "read"
+	BudgetWrite    Budget = "write"
+	BudgetEvaluate Budget = "evaluate"
+)
+
+// Charge is the number of tokens a request takes from a budget
+type Charge struct {
+	Budget Budget
+	Tokens int
+}
+
+// Rate is the sustained rate and the burst of a budget, a zero PerSecond does not limit it
+type Rate struct {
+	PerSecond float64
+	Burst     int
+}
+
+// RateLimitError is returned when a client exceeded a budget
+type RateLimitError struct {
+	Budget     Budget
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("rate limit of %s calls exceeded, retry in %s", e.Budget, e.RetryAfter.Round(time.Millisecond))
+}
+
+// RateLimiter holds a token bucket per client and budget
+type RateLimiter struct {
+	rates map[Budget]Rate
+
+	mu      sync.Mutex
+	buckets map[bucketKey]*bucket
+	sweptAt time.Time
+}
+
+type bucketKey struct {
+	client string
+	budget Budget
+}
+
+type bucket struct {
+	limiter *rate.Limiter
+	seenAt  time.Time
+}
+
+// NewRateLimiter creates a limiter with the rates of the budgets
+func NewRateLimiter(rates map[Budget]Rate) *RateLimiter {
+	return &RateLimiter{rates: rates, buckets: map[bucketKey]*bucket{}, sweptAt: time.Now()}
+}
+
+// Allow takes the tokens of the charges from the buckets of a client, or none of them and returns
+// a RateLimitError telling when they will be available, so that a denied batch does not use up budget.
+// A charge takes at least one token and at most the burst of its budget, which would never be available
+func (l *RateLimiter) Allow(client string, charges ...Charge) error {
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.sweep(now)
+
+	var taken []*rate.Reservation
+	for _, charge := range charges {
+		budget := charge.Budget
+		r, ok := l.rates[budget]
+		if !ok || r.PerSecond <= 0 {
+			continue
+		}
+
+		key := bucketKey{client: client, budget: budget}
+		b, ok := l.buckets[key]
+		if !ok {
+			b = &bucket{limiter: rate.NewLimiter(rate.Limit(r.PerSecond), max(r.Burst, 1))}
+			l.buckets[key] = b
+		}
+		b.seenAt = now
+
+		reservation := b.limiter.ReserveN(now, min(max(charge.Tokens, 1), b.limiter.Burst()))
+		if delay := reservation.DelayFrom(now); delay > 0 {
+			reservation.CancelAt(now)
+			for i := len(taken) - 1; i >= 0; i-- {
+				taken[i].CancelAt(now)
+			}
+			return &RateLimitError{Budget: budget, RetryAfter: delay}
+		}
+		taken = append(taken, reservation)
+	}
+
+	return nil
+}
+
+// sweep drops the buckets of the clients idle for a while, callers must hold the lock
+func (l *RateLimiter) sweep(now time.Time) {
+
+	if now.Sub(l.sweptAt) < idleBucket {
+		return
+	}
+	for key, b := range l.buckets {
+		if now.Sub(b.seenAt) > idleBucket {
+			delete(l.buckets, key)
+		}
+	}
+	l.sweptAt = now
+}
+
+// rpcMessage is the part of a JSON-RPC message the budgets depend on
+type rpcMessage struct {
+	Method string `json:"method"`
+	Params struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"`
+	} `json:"params"`
+}
+
+// RateLimit answers 429 Too Many Requests with a Retry-After header to the MCP requests of a client
+// over its budget. Clients are the authenticated principals, or their IP address otherwise, and charge
+// returns what a JSON-RPC method costs, with the name and arguments of the tool for tools/call, false if
+// unlimited. It reads the principal from the request context so it must be wrapped by Auth
+func RateLimit(next http.Handler, l *RateLimiter, charge func(method, tool string, arguments json.RawMessage) (Charge, bool)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		if r.Method != http.MethodPost || r.Body == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodySize))
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, fmt.Sprintf("request body larger than %d bytes", tooLarge.Limit), http.StatusRequestEntityTooLarge)
+			return
+		}
+		if err != nil {
+			http.Error(w, "failed to read body", http.StatusBadRequest)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		var charges []Charge
+		for _, msg := range rpcMessages(body) {
+			if c, ok := charge(msg.Method, msg.Params.Name, msg.Params.Arguments); ok {
+				charges = append(charges, c)
+			}
+		}
+
+		// the messages of a batch are allowed together, or the whole batch is refused
+		client := clientKey(r)
+		if err := l.Allow(client, charges...); err != nil {
+			rateErr := err.(*RateLimitError)
+			logger.WithContext(r.Context()).Warnf("Rate limited %d message(s) of %s: %v", len(charges), client, err)
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(rateErr.RetryAfter.Seconds()))))
+			http.Error(w, err.Error(), http.StatusTooManyRequests)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// clientKey identifies the client of a request by its principal, or by its IP address
+func clientKey(r *http.Request) string {
+
+	if p := auth.FromContext(r.Context()); p != nil {
+		return "principal:" + p.Name
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	return "ip:" + host
+}
+
+// rpcMessages decodes a JSON-RPC message or batch, the transport rejects what does not decode
+func rpcMessages(body []byte) []rpcMessage {
+
+	var batch []rpcMessage
+	if err := json.Unmarshal(body, &batch); err == nil {
+		return batch
+	}
+	var msg rpcMessage
+	if err := json.Unmarshal(body, &msg); err == nil {
+		return []rpcMessage{msg}
+	}
+
+	return nil
+}
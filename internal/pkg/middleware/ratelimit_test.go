@@ -0,0 +1,111 @@
+package middleware
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// slow is a rate that does not refill during a test
+const slow = 0.001
+
+func TestRateLimiterAllow(t *testing.T) {
+
+	l := NewRateLimiter(map[Budget]Rate{
+		BudgetRead:     {PerSecond: slow, Burst: 2},
+		BudgetWrite:    {PerSecond: 0, Burst: 1},
+		BudgetEvaluate: {PerSecond: slow, Burst: 10},
+	})
+
+	steps := []struct {
+		name    string
+		client  string
+		charges []Charge
+		refused Budget // budget refusing the charges, empty when allowed
+	}{
+		{"first read", "alice", []Charge{{BudgetRead, 1}}, ""},
+		{"second read", "alice", []Charge{{BudgetRead, 1}}, ""},
+		{"read over budget", "alice", []Charge{{BudgetRead, 1}}, BudgetRead},
+		{"other client", "bob", []Charge{{BudgetRead, 1}}, ""},
+		{"unlimited budget", "alice", []Charge{{BudgetWrite, 1}, {BudgetWrite, 1}, {BudgetWrite, 1}}, ""},
+		{"charge over the burst takes the whole bucket", "alice", []Charge{{BudgetEvaluate, 25}}, ""},
+		{"bucket emptied by the large charge", "alice", []Charge{{BudgetEvaluate, 1}}, BudgetEvaluate},
+		{"zero tokens take one", "bob", []Charge{{BudgetRead, 0}}, ""},
+		{"other client over budget", "bob", []Charge{{BudgetRead, 1}}, BudgetRead},
+	}
+	for _, step := range steps {
+		t.Run(step.name, func(t *testing.T) {
+			err := l.Allow(step.client, step.charges...)
+			if step.refused == "" {
+				if err != nil {
+					t.Fatalf("Allow() = %v, want nil", err)
+				}
+				return
+			}
+
+			var rateErr *RateLimitError
+			if !errors.As(err, &rateErr) {
+				t.Fatalf("Allow() = %v, want a RateLimitError", err)
+			}
+			if rateErr.Budget != step.refused || rateErr.RetryAfter <= 0 {
+				t.Fatalf("refused by %s retrying after %s, want %s with a delay", rateErr.Budget, rateErr.RetryAfter, step.refused)
+			}
+		})
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+
+	l := NewRateLimiter(map[Budget]Rate{
+		BudgetRead:  {PerSecond: slow, Burst: 2},
+		BudgetWrite: {PerSecond: slow, Burst: 1},
+	})
+	charge := func(method, tool string, _ json.RawMessage) (Charge, bool) {
+		switch {
+		case method != "tools/call":
+			return Charge{}, false
+		case tool == "write":
+			return Charge{Budget: BudgetWrite, Tokens: 1}, true
+		default:
+			return Charge{Budget: BudgetRead, Tokens: 1}, true
+		}
+	}
+	h := RateLimit(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}), l, charge)
+
+	call := func(tool string) string {
+		return `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"` + tool + `"}}`
+	}
+	batch := func(msgs ...string) string {
+		return "[" + strings.Join(msgs, ",") + "]"
+	}
+
+	steps := []struct {
+		name   string
+		body   string
+		status int
+	}{
+		{"write", call("write"), http.StatusOK},
+		{"write over budget", call("write"), http.StatusTooManyRequests},
+		{"batch refused by its write", batch(call("read"), call("read"), call("write")), http.StatusTooManyRequests},
+		{"reads of the refused batch refunded", batch(call("read"), call("read")), http.StatusOK},
+		{"read over budget", call("read"), http.StatusTooManyRequests},
+		{"unlimited method", `{"jsonrpc":"2.0","id":1,"method":"tools/list"}`, http.StatusOK},
+		{"body too large", `{"jsonrpc":"2.0","method":"tools/list","params":{"pad":"` + strings.Repeat("x", maxBodySize) + `"}}`, http.StatusRequestEntityTooLarge},
+	}
+	for _, step := range steps {
+		t.Run(step.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/mcp", strings.NewReader(step.body)))
+
+			if w.Code != step.status {
+				t.Fatalf("status %d, want %d: %s", w.Code, step.status, w.Body)
+			}
+			if retry := w.Header().Get("Retry-After"); (step.status == http.StatusTooManyRequests) != (retry != "") {
+				t.Fatalf("Retry-After %q with status %d", retry, w.Code)
+			}
+		})
+	}
+}
@@ -8,15 +8,17 @@ import (
 	"github.com/hungpdn/mcp2grule/internal/utils"
 )
 
-// memory is an in-memory implementation of IRulesetStorage interface
+// memory is an in-memory implementation of IRulesetStorage and IPipelineStorage interfaces
 type memory struct {
 	mu sync.RWMutex
 	m  map[string]Ruleset
+	h  map[string][]Revision // revisions per ruleset, oldest first
+	p  map[string]Pipeline
 }
 
 // NewMemory creates a new memory storage
 func NewMemory() *memory {
-	return &memory{m: map[string]Ruleset{}}
+	return &memory{m: map[string]Ruleset{}, h: map[string][]Revision{}, p: map[string]Pipeline{}}
 }
 
 // key generates map key for a ruleset name
@@ -60,10 +62,15 @@ func (s *memory) Create(ctx context.Context, rule Ruleset) (string, error) {
 	}
 
 	rule.ID = utils.NewULID()
+	rule.Version = 1
+	if rule.Tags == nil {
+		rule.Tags = []string{}
+	}
 	rule.CreatedAt = time.Now().Unix()
 	rule.UpdatedAt = time.Now().Unix()
 
 	s.m[s.key(rule.Name)] = rule
+	s.addRevision(rule)
 
 	return rule.ID, nil
 }
@@ -74,12 +81,21 @@ func (s *memory) Update(ctx context.Context, name string, rule Ruleset) error {
 	defer s.mu.Unlock()
 
 	k := s.key(name)
-	if _, ok := s.m[k]; !ok {
+	current, ok := s.m[k]
+	if !ok {
 		return ErrNotFound
 	}
+	if current.Version != rule.Version {
+		return ErrConflict
+	}
 
+	rule.Version++
 	rule.UpdatedAt = time.Now().Unix()
+	if rule.Tags == nil {
+		rule.Tags = []string{}
+	}
 	s.m[k] = rule
+	s.addRevision(rule)
 
 	return nil
 }
@@ -94,6 +110,132 @@ func (s *memory) Delete(ctx context.Context, name string) error {
 		return ErrNotFound
 	}
 	delete(s.m, k)
+	delete(s.h, k)
+
+	return nil
+}
+
+// GetRevisions returns the revisions of a ruleset, oldest first
+func (s *memory) GetRevisions(ctx context.Context, name string) ([]Revision, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	h, ok := s.h[s.key(name)]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	out := make([]Revision, len(h))
+	copy(out, h)
+
+	return out, nil
+}
+
+// GetRevision returns a single revision of a ruleset
+func (s *memory) GetRevision(ctx context.Context, name string, revision int) (*Revision, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	h := s.h[s.key(name)]
+	if revision < 1 || revision > len(h) {
+		return nil, ErrNotFound
+	}
+
+	r := h[revision-1]
+	return &r, nil
+}
+
+// addRevision appends the current state of rule to its revision history,
+// callers must hold the write lock
+func (s *memory) addRevision(rule Ruleset) {
+	k := s.key(rule.Name)
+	s.h[k] = append(s.h[k], Revision{
+		RulesetName: rule.Name,
+		Revision:    len(s.h[k]) + 1,
+		Author:      rule.UpdatedBy,
+		Description: rule.Description,
+		Salience:    rule.Salience,
+		GRL:         rule.GRL,
+		Tags:        rule.Tags,
+		FactSchema:  rule.FactSchema,
+		CreatedAt:   rule.UpdatedAt,
+	})
+}
+
+// GetAllPipelines returns all pipelines
+func (s *memory) GetAllPipelines(ctx context.Context) ([]Pipeline, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	out := make([]Pipeline, 0, len(s.p))
+	for _, v := range s.p {
+		out = append(out, v)
+	}
+
+	return out, nil
+}
+
+// GetPipeline returns a pipeline by name
+func (s *memory) GetPipeline(ctx context.Context, name string) (*Pipeline, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	p, ok := s.p[s.key(name)]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	return &p, nil
+}
+
+// CreatePipeline creates a new pipeline
+func (s *memory) CreatePipeline(ctx context.Context, pipeline Pipeline) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.p[s.key(pipeline.Name)]; ok {
+		return "", ErrAlreadyExists
+	}
+
+	pipeline.ID = utils.NewULID()
+	pipeline.CreatedAt = time.Now().Unix()
+	pipeline.UpdatedAt = pipeline.CreatedAt
+
+	s.p[s.key(pipeline.Name)] = pipeline
+
+	return pipeline.ID, nil
+}
+
+// UpdatePipeline updates an existing pipeline
+func (s *memory) UpdatePipeline(ctx context.Context, name string, pipeline Pipeline) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k := s.key(name)
+	current, ok := s.p[k]
+	if !ok {
+		return ErrNotFound
+	}
+
+	pipeline.ID = current.ID
+	pipeline.Name = current.Name
+	pipeline.CreatedAt = current.CreatedAt
+	pipeline.UpdatedAt = time.Now().Unix()
+	s.p[k] = pipeline
+
+	return nil
+}
+
+// DeletePipeline deletes a pipeline by name
+func (s *memory) DeletePipeline(ctx context.Context, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k := s.key(name)
+	if _, ok := s.p[k]; !ok {
+		return ErrNotFound
+	}
+	delete(s.p, k)
 
 	return nil
 }
@@ -0,0 +1,53 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestMemoryUpdateVersionConflict(t *testing.T) {
+
+	ctx := context.Background()
+	s := NewMemory()
+	if _, err := s.Create(ctx, Ruleset{Name: "loan", GRL: "v1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		ruleset string
+		version int
+		want    error
+		stored  int // version stored after the update
+	}{
+		{"current version", "loan", 1, nil, 2},
+		{"stale version", "loan", 1, ErrConflict, 2},
+		{"version ahead", "loan", 5, ErrConflict, 2},
+		{"next current version", "loan", 2, nil, 3},
+		{"missing ruleset", "nope", 1, ErrNotFound, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := s.Update(ctx, tt.ruleset, Ruleset{Name: tt.ruleset, GRL: tt.name, Version: tt.version})
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("Update() = %v, want %v", err, tt.want)
+			}
+
+			rule, err := s.GetByName(ctx, "loan")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if rule.Version != tt.stored {
+				t.Fatalf("stored version = %d, want %d", rule.Version, tt.stored)
+			}
+			revisions, err := s.GetRevisions(ctx, "loan")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(revisions) != tt.stored {
+				t.Fatalf("%d revisions, want %d: a refused update must not record one", len(revisions), tt.stored)
+			}
+		})
+	}
+}
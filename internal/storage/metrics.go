@@ -0,0 +1,83 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/hungpdn/mcp2grule/internal/pkg/metrics"
+)
+
+// Instrument wraps storages so that the duration of every operation is recorded
+func Instrument(rulesets IRulesetStorage, pipelines IPipelineStorage) (IRulesetStorage, IPipelineStorage) {
+	return &instrumentedRulesets{next: rulesets}, &instrumentedPipelines{next: pipelines}
+}
+
+// instrumentedRulesets records the duration of the operations of a ruleset storage
+type instrumentedRulesets struct {
+	next IRulesetStorage
+}
+
+func (s *instrumentedRulesets) GetAll(ctx context.Context) (rules []Ruleset, err error) {
+	defer func(start time.Time) { metrics.ObserveStorage("get_all", start, err) }(time.Now())
+	return s.next.GetAll(ctx)
+}
+
+func (s *instrumentedRulesets) GetByName(ctx context.Context, name string) (rule *Ruleset, err error) {
+	defer func(start time.Time) { metrics.ObserveStorage("get_by_name", start, err) }(time.Now())
+	return s.next.GetByName(ctx, name)
+}
+
+func (s *instrumentedRulesets) Create(ctx context.Context, rule Ruleset) (id string, err error) {
+	defer func(start time.Time) { metrics.ObserveStorage("create", start, err) }(time.Now())
+	return s.next.Create(ctx, rule)
+}
+
+func (s *instrumentedRulesets) Update(ctx context.Context, name string, rule Ruleset) (err error) {
+	defer func(start time.Time) { metrics.ObserveStorage("update", start, err) }(time.Now())
+	return s.next.Update(ctx, name, rule)
+}
+
+func (s *instrumentedRulesets) Delete(ctx context.Context, name string) (err error) {
+	defer func(start time.Time) { metrics.ObserveStorage("delete", start, err) }(time.Now())
+	return s.next.Delete(ctx, name)
+}
+
+func (s *instrumentedRulesets) GetRevisions(ctx context.Context, name string) (revisions []Revision, err error) {
+	defer func(start time.Time) { metrics.ObserveStorage("get_revisions", start, err) }(time.Now())
+	return s.next.GetRevisions(ctx, name)
+}
+
+func (s *instrumentedRulesets) GetRevision(ctx context.Context, name string, revision int) (rev *Revision, err error) {
+	defer func(start time.Time) { metrics.ObserveStorage("get_revision", start, err) }(time.Now())
+	return s.next.GetRevision(ctx, name, revision)
+}
+
+// instrumentedPipelines records the duration of the operations of a pipeline storage
+type instrumentedPipelines struct {
+	next IPipelineStorage
+}
+
+func (s *instrumentedPipelines) GetAllPipelines(ctx context.Context) (pipelines []Pipeline, err error) {
+	defer func(start time.Time) { metrics.ObserveStorage("get_all_pipelines", start, err) }(time.Now())
+	return s.next.GetAllPipelines(ctx)
+}
+
+func (s *instrumentedPipelines) GetPipeline(ctx context.Context, name string) (pipeline *Pipeline, err error) {
+	defer func(start time.Time) { metrics.ObserveStorage("get_pipeline", start, err) }(time.Now())
+	return s.next.GetPipeline(ctx, name)
+}
+
+func (s *instrumentedPipelines) CreatePipeline(ctx context.Context, pipeline Pipeline) (id string, err error) {
+	defer func(start time.Time) { metrics.ObserveStorage("create_pipeline", start, err) }(time.Now())
+	return s.next.CreatePipeline(ctx, pipeline)
+}
+
+func (s *instrumentedPipelines) UpdatePipeline(ctx context.Context, name string, pipeline Pipeline) (err error) {
+	defer func(start time.Time) { metrics.ObserveStorage("update_pipeline", start, err) }(time.Now())
+	return s.next.UpdatePipeline(ctx, name, pipeline)
+}
+
+func (s *instrumentedPipelines) DeletePipeline(ctx context.Context, name string) (err error) {
+	defer func(start time.Time) { metrics.ObserveStorage("delete_pipeline", start, err) }(time.Now())
+	return s.next.DeletePipeline(ctx, name)
+}
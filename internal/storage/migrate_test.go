@@ -0,0 +1,81 @@
+package storage
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/hungpdn/mcp2grule/internal/config"
+)
+
+func TestSQLiteMigrateRoundTrip(t *testing.T) {
+
+	ctx := context.Background()
+	db, err := OpenSQLite(config.SQLite{Path: filepath.Join(t.TempDir(), "mcp2grule.db")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	m, err := NewMigrator(db, DialectSQLite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	total := len(m.migrations)
+
+	pending := func(t *testing.T) int {
+		t.Helper()
+		status, err := m.Status(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		count := 0
+		for _, s := range status {
+			if !s.Applied {
+				count++
+			}
+		}
+		return count
+	}
+
+	steps := []struct {
+		name    string
+		run     func() (int, error)
+		want    int // migrations applied or reverted by the step
+		pending int // migrations pending after the step
+	}{
+		{"up from scratch", func() (int, error) { return m.Up(ctx) }, total, 0},
+		{"up again is a no-op", func() (int, error) { return m.Up(ctx) }, 0, 0},
+		{"down one", func() (int, error) { return m.Down(ctx, 1) }, 1, 1},
+		{"up the reverted one", func() (int, error) { return m.Up(ctx) }, 1, 0},
+		{"down all", func() (int, error) { return m.Down(ctx, total+1) }, total, total},
+		{"up from scratch again", func() (int, error) { return m.Up(ctx) }, total, 0},
+	}
+	for _, step := range steps {
+		t.Run(step.name, func(t *testing.T) {
+			n, err := step.run()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n != step.want {
+				t.Fatalf("%d migration(s), want %d", n, step.want)
+			}
+			if p := pending(t); p != step.pending {
+				t.Fatalf("%d pending migration(s), want %d", p, step.pending)
+			}
+		})
+	}
+
+	// the migrated schema serves the storage
+	s := NewSQLite(db)
+	if _, err := s.Create(ctx, Ruleset{Name: "loan", GRL: "v1"}); err != nil {
+		t.Fatal(err)
+	}
+	rule, err := s.GetByName(ctx, "loan")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rule.GRL != "v1" {
+		t.Fatalf("stored GRL = %q, want \"v1\"", rule.GRL)
+	}
+}
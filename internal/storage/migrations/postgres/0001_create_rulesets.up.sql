@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS rulesets (
+	id          TEXT    PRIMARY KEY,
+	name        TEXT    NOT NULL UNIQUE,
+	description TEXT    NOT NULL DEFAULT '',
+	salience    INTEGER NOT NULL DEFAULT 0,
+	grl         TEXT    NOT NULL,
+	created_at  BIGINT  NOT NULL,
+	updated_at  BIGINT  NOT NULL
+);
@@ -0,0 +1,8 @@
+ALTER TABLE ruleset_revisions ADD COLUMN tags TEXT NOT NULL DEFAULT '[]'; -- JSON array of tags
+ALTER TABLE ruleset_revisions ADD COLUMN fact_schema TEXT NOT NULL DEFAULT ''; -- JSON Schema of the facts, empty when unchecked
+
+-- Existing revisions get the current tags and fact schema of their ruleset, which rollbacks kept so far
+UPDATE ruleset_revisions SET
+	tags = (SELECT tags FROM rulesets WHERE rulesets.name = ruleset_revisions.ruleset_name),
+	fact_schema = (SELECT fact_schema FROM rulesets WHERE rulesets.name = ruleset_revisions.ruleset_name)
+WHERE ruleset_name IN (SELECT name FROM rulesets);
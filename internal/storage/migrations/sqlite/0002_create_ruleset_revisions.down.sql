@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS ruleset_revisions;
+
+ALTER TABLE rulesets DROP COLUMN updated_by;
@@ -0,0 +1,16 @@
+ALTER TABLE rulesets ADD COLUMN updated_by TEXT NOT NULL DEFAULT '';
+
+CREATE TABLE IF NOT EXISTS ruleset_revisions (
+	ruleset_name TEXT    NOT NULL,
+	revision     INTEGER NOT NULL,
+	author       TEXT    NOT NULL DEFAULT '',
+	description  TEXT    NOT NULL DEFAULT '',
+	salience     INTEGER NOT NULL DEFAULT 0,
+	grl          TEXT    NOT NULL,
+	created_at   INTEGER NOT NULL,
+	PRIMARY KEY (ruleset_name, revision)
+);
+
+-- Existing rulesets start their history at their current state
+INSERT INTO ruleset_revisions (ruleset_name, revision, author, description, salience, grl, created_at)
+SELECT name, 1, '', description, salience, grl, updated_at FROM rulesets;
@@ -0,0 +1,5 @@
+ALTER TABLE rulesets ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+
+-- The version of existing rulesets continues from their latest revision
+UPDATE rulesets SET version = COALESCE(
+	(SELECT MAX(revision) FROM ruleset_revisions WHERE ruleset_revisions.ruleset_name = rulesets.name), 1);
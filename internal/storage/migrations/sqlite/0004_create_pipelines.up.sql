@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS pipelines (
+	id          TEXT    PRIMARY KEY,
+	name        TEXT    NOT NULL UNIQUE,
+	description TEXT    NOT NULL DEFAULT '',
+	stages      TEXT    NOT NULL, -- JSON array of stages
+	created_at  INTEGER NOT NULL,
+	updated_at  INTEGER NOT NULL
+);
@@ -0,0 +1 @@
+ALTER TABLE rulesets ADD COLUMN tags TEXT NOT NULL DEFAULT '[]'; -- JSON array of tags
@@ -0,0 +1 @@
+ALTER TABLE rulesets DROP COLUMN fact_schema;
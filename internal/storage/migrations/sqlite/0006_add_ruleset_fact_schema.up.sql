@@ -0,0 +1 @@
+ALTER TABLE rulesets ADD COLUMN fact_schema TEXT NOT NULL DEFAULT ''; -- JSON Schema of the facts, empty when unchecked
@@ -0,0 +1,2 @@
+ALTER TABLE ruleset_revisions DROP COLUMN fact_schema;
+ALTER TABLE ruleset_revisions DROP COLUMN tags;
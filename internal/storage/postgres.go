@@ -1,3 +1,65 @@
 package storage
 
-// Postgres-specific implementation will go here in the future.
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hungpdn/mcp2grule/internal/config"
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5/pgconn"
+	_ "github.com/jackc/pgx/v5/stdlib" // registers the "pgx" database/sql driver
+)
+
+// postgres is a PostgreSQL implementation of IRulesetStorage and IPipelineStorage interfaces
+type postgres struct {
+	sqlStore
+}
+
+// OpenPostgres opens a pooled connection to the Postgres database configured in cfg
+func OpenPostgres(ctx context.Context, cfg config.Postgres) (*sql.DB, error) {
+	db, err := sql.Open("pgx", cfg.DSN)
+	if err != nil {
+		return nil, err
+	}
+
+	db.SetMaxOpenConns(cfg.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.MaxIdleConns)
+	db.SetConnMaxLifetime(time.Duration(cfg.ConnMaxLifetime) * time.Second)
+
+	if err := db.PingContext(ctx); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+
+	return db, nil
+}
+
+// NewPostgres creates a new Postgres storage, the schema is managed by Migrator
+func NewPostgres(db *sql.DB) *postgres {
+	return &postgres{sqlStore{db: db, dialect: DialectPostgres, mapErr: postgresError}}
+}
+
+// postgresError maps Postgres errors onto the common storage errors
+func postgresError(err error) error {
+	if err == nil || isStorageError(err) {
+		return err
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case pgerrcode.UniqueViolation:
+			return ErrAlreadyExists
+		case pgerrcode.InvalidTextRepresentation, pgerrcode.StringDataRightTruncationDataException:
+			return fmt.Errorf("%w: %v", ErrInvalidInput, pgErr.Message)
+		}
+	}
+
+	return fmt.Errorf("%w: %v", ErrDatabase, err)
+}
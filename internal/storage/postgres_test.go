@@ -0,0 +1,79 @@
+package storage
+
+import (
+	"context"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/hungpdn/mcp2grule/internal/config"
+	"github.com/hungpdn/mcp2grule/internal/utils"
+)
+
+// postgresTestDSN names the environment variable holding the URL of the Postgres database
+// of the integration test, e.g. the one started by make postgres-up
+const postgresTestDSN = "POSTGRES_TEST_DSN"
+
+func TestPostgresStorage(t *testing.T) {
+
+	dsn := os.Getenv(postgresTestDSN)
+	if dsn == "" {
+		t.Skipf("%s is not set, start Postgres with make postgres-up and set it to run the test", postgresTestDSN)
+	}
+	ctx := context.Background()
+
+	// the test runs in a schema of its own, dropped at the end, leaving the database untouched
+	admin, err := OpenPostgres(ctx, config.Postgres{DSN: dsn, MaxOpenConns: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer admin.Close()
+	schema := "mcp2grule_test_" + strings.ToLower(utils.NewULID())
+	if _, err := admin.ExecContext(ctx, `CREATE SCHEMA `+schema); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if _, err := admin.ExecContext(context.Background(), `DROP SCHEMA `+schema+` CASCADE`); err != nil {
+			t.Errorf("dropping schema %s: %v", schema, err)
+		}
+	}()
+
+	u, err := url.Parse(dsn)
+	if err != nil {
+		t.Fatalf("%s must be a postgres:// URL: %v", postgresTestDSN, err)
+	}
+	q := u.Query()
+	q.Set("search_path", schema)
+	u.RawQuery = q.Encode()
+
+	db, err := OpenPostgres(ctx, config.Postgres{DSN: u.String(), MaxOpenConns: 4, MaxIdleConns: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	m, err := NewMigrator(db, DialectPostgres)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	testStorage(t, func(t *testing.T) store {
+		if _, err := db.ExecContext(ctx, `TRUNCATE rulesets, ruleset_revisions, pipelines`); err != nil {
+			t.Fatal(err)
+		}
+		return NewPostgres(db)
+	})
+
+	// every migration reverts on Postgres too
+	n, err := m.Down(ctx, len(m.migrations))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != len(m.migrations) {
+		t.Fatalf("%d migration(s) reverted, want %d", n, len(m.migrations))
+	}
+}
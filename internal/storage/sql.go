@@ -0,0 +1,345 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/hungpdn/mcp2grule/internal/utils"
+)
+
+// rulesetColumns lists the rulesets columns in the order scanned by scanRuleset
+const rulesetColumns = `id, name, description, salience, grl, tags, fact_schema, updated_by, version, created_at, updated_at`
+
+// revisionColumns lists the ruleset_revisions columns in the order scanned by scanRevision
+const revisionColumns = `ruleset_name, revision, author, description, salience, grl, tags, fact_schema, created_at`
+
+// scanner is implemented by *sql.Row and *sql.Rows
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+// sqlStore implements IRulesetStorage and IPipelineStorage on top of database/sql,
+// the sqlite and postgres storages provide the dialect and the error mapping
+type sqlStore struct {
+	db      *sql.DB
+	dialect Dialect
+	mapErr  func(error) error
+}
+
+// GetAll returns all rulesets
+func (s *sqlStore) GetAll(ctx context.Context) ([]Ruleset, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+rulesetColumns+` FROM rulesets ORDER BY name`)
+	if err != nil {
+		return nil, s.mapErr(err)
+	}
+	defer rows.Close()
+
+	out := make([]Ruleset, 0)
+	for rows.Next() {
+		r, err := scanRuleset(rows)
+		if err != nil {
+			return nil, s.mapErr(err)
+		}
+		out = append(out, *r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, s.mapErr(err)
+	}
+
+	return out, nil
+}
+
+// GetByName returns a ruleset by name
+func (s *sqlStore) GetByName(ctx context.Context, name string) (*Ruleset, error) {
+	row := s.db.QueryRowContext(ctx, s.rebind(`SELECT `+rulesetColumns+` FROM rulesets WHERE name = ?`), name)
+
+	r, err := scanRuleset(row)
+	if err != nil {
+		return nil, s.mapErr(err)
+	}
+
+	return r, nil
+}
+
+// Create creates a new ruleset and records its first revision
+func (s *sqlStore) Create(ctx context.Context, rule Ruleset) (string, error) {
+	rule.ID = utils.NewULID()
+	rule.Version = 1
+	rule.CreatedAt = time.Now().Unix()
+	rule.UpdatedAt = rule.CreatedAt
+
+	tags, err := marshalTags(rule.Tags)
+	if err != nil {
+		return "", err
+	}
+	schema, err := marshalSchema(rule.FactSchema)
+	if err != nil {
+		return "", err
+	}
+
+	err = s.tx(ctx, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, s.rebind(`INSERT INTO rulesets (`+rulesetColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`),
+			rule.ID, rule.Name, rule.Description, rule.Salience, rule.GRL, tags, schema, rule.UpdatedBy, rule.Version, rule.CreatedAt, rule.UpdatedAt)
+		if err != nil {
+			return err
+		}
+
+		return s.addRevision(ctx, tx, rule)
+	})
+	if err != nil {
+		return "", s.mapErr(err)
+	}
+
+	return rule.ID, nil
+}
+
+// Update updates an existing ruleset at the expected version and records a new revision
+func (s *sqlStore) Update(ctx context.Context, name string, rule Ruleset) error {
+	rule.Name = name
+	rule.UpdatedAt = time.Now().Unix()
+
+	tags, err := marshalTags(rule.Tags)
+	if err != nil {
+		return err
+	}
+	schema, err := marshalSchema(rule.FactSchema)
+	if err != nil {
+		return err
+	}
+
+	err = s.tx(ctx, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx, s.rebind(`UPDATE rulesets SET description = ?, salience = ?, grl = ?, tags = ?, fact_schema = ?, updated_by = ?, updated_at = ?, version = version + 1 WHERE name = ? AND version = ?`),
+			rule.Description, rule.Salience, rule.GRL, tags, schema, rule.UpdatedBy, rule.UpdatedAt, name, rule.Version)
+		if err != nil {
+			return err
+		}
+		if err := affected(res); errors.Is(err, ErrNotFound) {
+			return s.conflict(ctx, tx, name)
+		} else if err != nil {
+			return err
+		}
+
+		return s.addRevision(ctx, tx, rule)
+	})
+
+	return s.mapErr(err)
+}
+
+// Delete deletes a ruleset and its revisions by name
+func (s *sqlStore) Delete(ctx context.Context, name string) error {
+	err := s.tx(ctx, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx, s.rebind(`DELETE FROM rulesets WHERE name = ?`), name)
+		if err != nil {
+			return err
+		}
+		if err := affected(res); err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, s.rebind(`DELETE FROM ruleset_revisions WHERE ruleset_name = ?`), name)
+		return err
+	})
+
+	return s.mapErr(err)
+}
+
+// GetRevisions returns the revisions of a ruleset, oldest first
+func (s *sqlStore) GetRevisions(ctx context.Context, name string) ([]Revision, error) {
+	rows, err := s.db.QueryContext(ctx,
+		s.rebind(`SELECT `+revisionColumns+` FROM ruleset_revisions WHERE ruleset_name = ? ORDER BY revision`), name)
+	if err != nil {
+		return nil, s.mapErr(err)
+	}
+	defer rows.Close()
+
+	out := make([]Revision, 0)
+	for rows.Next() {
+		r, err := scanRevision(rows)
+		if err != nil {
+			return nil, s.mapErr(err)
+		}
+		out = append(out, *r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, s.mapErr(err)
+	}
+	if len(out) == 0 {
+		return nil, ErrNotFound
+	}
+
+	return out, nil
+}
+
+// GetRevision returns a single revision of a ruleset
+func (s *sqlStore) GetRevision(ctx context.Context, name string, revision int) (*Revision, error) {
+	row := s.db.QueryRowContext(ctx,
+		s.rebind(`SELECT `+revisionColumns+` FROM ruleset_revisions WHERE ruleset_name = ? AND revision = ?`), name, revision)
+
+	r, err := scanRevision(row)
+	if err != nil {
+		return nil, s.mapErr(err)
+	}
+
+	return r, nil
+}
+
+// Close closes the underlying database
+func (s *sqlStore) Close() error {
+	return s.db.Close()
+}
+
+// conflict tells apart a missing ruleset from a version mismatch after an update touched no row
+func (s *sqlStore) conflict(ctx context.Context, tx *sql.Tx, name string) error {
+	var exists int
+	err := tx.QueryRowContext(ctx, s.rebind(`SELECT 1 FROM rulesets WHERE name = ?`), name).Scan(&exists)
+	if err != nil {
+		return err
+	}
+
+	return ErrConflict
+}
+
+// addRevision appends the current state of rule to its revision history
+func (s *sqlStore) addRevision(ctx context.Context, tx *sql.Tx, rule Ruleset) error {
+	tags, err := marshalTags(rule.Tags)
+	if err != nil {
+		return err
+	}
+	schema, err := marshalSchema(rule.FactSchema)
+	if err != nil {
+		return err
+	}
+
+	var next int
+	err = tx.QueryRowContext(ctx,
+		s.rebind(`SELECT COALESCE(MAX(revision), 0) + 1 FROM ruleset_revisions WHERE ruleset_name = ?`), rule.Name).
+		Scan(&next)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, s.rebind(`INSERT INTO ruleset_revisions (`+revisionColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`),
+		rule.Name, next, rule.UpdatedBy, rule.Description, rule.Salience, rule.GRL, tags, schema, rule.UpdatedAt)
+
+	return err
+}
+
+// tx runs fn in a transaction, committing when it succeeds
+func (s *sqlStore) tx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// rebind rewrites ? placeholders into the placeholder syntax of the dialect
+func (s *sqlStore) rebind(query string) string {
+	return rebind(s.dialect, query)
+}
+
+// rebind rewrites ? placeholders into the placeholder syntax of dialect
+func rebind(dialect Dialect, query string) string {
+	if dialect != DialectPostgres {
+		return query
+	}
+
+	out := make([]byte, 0, len(query)+8)
+	n := 0
+	for i := 0; i < len(query); i++ {
+		if query[i] == '?' {
+			n++
+			out = append(out, '$')
+			out = strconv.AppendInt(out, int64(n), 10)
+			continue
+		}
+		out = append(out, query[i])
+	}
+
+	return string(out)
+}
+
+// affected returns ErrNotFound when a statement did not touch any row
+func affected(res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// scanRuleset reads a row selected with rulesetColumns
+func scanRuleset(row scanner) (*Ruleset, error) {
+	var r Ruleset
+	var tags, schema string
+	err := row.Scan(&r.ID, &r.Name, &r.Description, &r.Salience, &r.GRL, &tags, &schema, &r.UpdatedBy, &r.Version, &r.CreatedAt, &r.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal([]byte(tags), &r.Tags); err != nil {
+		return nil, err
+	}
+	if schema != "" {
+		if err := json.Unmarshal([]byte(schema), &r.FactSchema); err != nil {
+			return nil, err
+		}
+	}
+
+	return &r, nil
+}
+
+// marshalTags encodes tags into the JSON array stored in the tags column
+func marshalTags(tags []string) (string, error) {
+	if tags == nil {
+		tags = []string{}
+	}
+
+	b, err := json.Marshal(tags)
+	return string(b), err
+}
+
+// marshalSchema encodes a fact schema into the fact_schema column, empty when there is none
+func marshalSchema(schema map[string]any) (string, error) {
+	if schema == nil {
+		return "", nil
+	}
+
+	b, err := json.Marshal(schema)
+	return string(b), err
+}
+
+// scanRevision reads a row selected with revisionColumns
+func scanRevision(row scanner) (*Revision, error) {
+	var r Revision
+	var tags, schema string
+	err := row.Scan(&r.RulesetName, &r.Revision, &r.Author, &r.Description, &r.Salience, &r.GRL, &tags, &schema, &r.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal([]byte(tags), &r.Tags); err != nil {
+		return nil, err
+	}
+	if schema != "" {
+		if err := json.Unmarshal([]byte(schema), &r.FactSchema); err != nil {
+			return nil, err
+		}
+	}
+
+	return &r, nil
+}
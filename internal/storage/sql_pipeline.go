@@ -0,0 +1,111 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/hungpdn/mcp2grule/internal/utils"
+)
+
+// pipelineColumns lists the pipelines columns in the order scanned by scanPipeline
+const pipelineColumns = `id, name, description, stages, created_at, updated_at`
+
+// GetAllPipelines returns all pipelines
+func (s *sqlStore) GetAllPipelines(ctx context.Context) ([]Pipeline, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+pipelineColumns+` FROM pipelines ORDER BY name`)
+	if err != nil {
+		return nil, s.mapErr(err)
+	}
+	defer rows.Close()
+
+	out := make([]Pipeline, 0)
+	for rows.Next() {
+		p, err := scanPipeline(rows)
+		if err != nil {
+			return nil, s.mapErr(err)
+		}
+		out = append(out, *p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, s.mapErr(err)
+	}
+
+	return out, nil
+}
+
+// GetPipeline returns a pipeline by name
+func (s *sqlStore) GetPipeline(ctx context.Context, name string) (*Pipeline, error) {
+	row := s.db.QueryRowContext(ctx, s.rebind(`SELECT `+pipelineColumns+` FROM pipelines WHERE name = ?`), name)
+
+	p, err := scanPipeline(row)
+	if err != nil {
+		return nil, s.mapErr(err)
+	}
+
+	return p, nil
+}
+
+// CreatePipeline creates a new pipeline
+func (s *sqlStore) CreatePipeline(ctx context.Context, pipeline Pipeline) (string, error) {
+	pipeline.ID = utils.NewULID()
+	pipeline.CreatedAt = time.Now().Unix()
+	pipeline.UpdatedAt = pipeline.CreatedAt
+
+	stages, err := json.Marshal(pipeline.Stages)
+	if err != nil {
+		return "", err
+	}
+
+	_, err = s.db.ExecContext(ctx, s.rebind(`INSERT INTO pipelines (`+pipelineColumns+`) VALUES (?, ?, ?, ?, ?, ?)`),
+		pipeline.ID, pipeline.Name, pipeline.Description, string(stages), pipeline.CreatedAt, pipeline.UpdatedAt)
+	if err != nil {
+		return "", s.mapErr(err)
+	}
+
+	return pipeline.ID, nil
+}
+
+// UpdatePipeline updates an existing pipeline
+func (s *sqlStore) UpdatePipeline(ctx context.Context, name string, pipeline Pipeline) error {
+	pipeline.UpdatedAt = time.Now().Unix()
+
+	stages, err := json.Marshal(pipeline.Stages)
+	if err != nil {
+		return err
+	}
+
+	res, err := s.db.ExecContext(ctx, s.rebind(`UPDATE pipelines SET description = ?, stages = ?, updated_at = ? WHERE name = ?`),
+		pipeline.Description, string(stages), pipeline.UpdatedAt, name)
+	if err != nil {
+		return s.mapErr(err)
+	}
+
+	return s.mapErr(affected(res))
+}
+
+// DeletePipeline deletes a pipeline by name
+func (s *sqlStore) DeletePipeline(ctx context.Context, name string) error {
+	res, err := s.db.ExecContext(ctx, s.rebind(`DELETE FROM pipelines WHERE name = ?`), name)
+	if err != nil {
+		return s.mapErr(err)
+	}
+
+	return s.mapErr(affected(res))
+}
+
+// scanPipeline reads a row selected with pipelineColumns
+func scanPipeline(row scanner) (*Pipeline, error) {
+	var p Pipeline
+	var stages string
+	err := row.Scan(&p.ID, &p.Name, &p.Description, &stages, &p.CreatedAt, &p.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal([]byte(stages), &p.Stages); err != nil {
+		return nil, err
+	}
+
+	return &p, nil
+}
@@ -0,0 +1,61 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/hungpdn/mcp2grule/internal/config"
+	sqlitedriver "modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// sqlite is a SQLite implementation of IRulesetStorage and IPipelineStorage interfaces
+type sqlite struct {
+	sqlStore
+}
+
+// OpenSQLite opens the SQLite database file configured in cfg
+func OpenSQLite(cfg config.SQLite) (*sql.DB, error) {
+	dsn := fmt.Sprintf("file:%s?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)", cfg.Path)
+
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	// SQLite allows a single writer, serialize access through one connection
+	db.SetMaxOpenConns(1)
+
+	if err := db.Ping(); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+
+	return db, nil
+}
+
+// NewSQLite creates a new SQLite storage, the schema is managed by Migrator
+func NewSQLite(db *sql.DB) *sqlite {
+	return &sqlite{sqlStore{db: db, dialect: DialectSQLite, mapErr: sqliteError}}
+}
+
+// sqliteError maps SQLite errors onto the common storage errors
+func sqliteError(err error) error {
+	if err == nil || isStorageError(err) {
+		return err
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+
+	var sqliteErr *sqlitedriver.Error
+	if errors.As(err, &sqliteErr) {
+		switch sqliteErr.Code() {
+		case sqlite3.SQLITE_CONSTRAINT_UNIQUE, sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY:
+			return ErrAlreadyExists
+		}
+	}
+
+	return fmt.Errorf("%w: %v", ErrDatabase, err)
+}
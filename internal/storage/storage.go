@@ -11,17 +11,66 @@ var (
 	ErrAlreadyExists = errors.New("record already exists")
 	ErrInvalidInput  = errors.New("invalid input")
 	ErrDatabase      = errors.New("database error")
+	ErrConflict      = errors.New("version conflict")
 )
 
+// isStorageError reports whether err already is one of the common storage errors
+func isStorageError(err error) bool {
+	return errors.Is(err, ErrNotFound) ||
+		errors.Is(err, ErrAlreadyExists) ||
+		errors.Is(err, ErrInvalidInput) ||
+		errors.Is(err, ErrDatabase) ||
+		errors.Is(err, ErrConflict)
+}
+
 // Ruleset represents the structure of a business rule in the database.
 type Ruleset struct {
-	ID          string `json:"id"`
-	Name        string `json:"name"`
-	Description string `json:"description"`
-	Salience    int    `json:"salience"`   // Priority of the rule
-	GRL         string `json:"grl"`        // The actual GRL content
-	CreatedAt   int64  `json:"created_at"` // Unix timestamp
-	UpdatedAt   int64  `json:"updated_at"` // Unix timestamp
+	ID          string         `json:"id"`
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	Salience    int            `json:"salience"`              // Priority of the ruleset when evaluated together with others
+	GRL         string         `json:"grl"`                   // The actual GRL content
+	Tags        []string       `json:"tags"`                  // Labels selecting rulesets evaluated together
+	FactSchema  map[string]any `json:"fact_schema,omitempty"` // JSON Schema the facts must match, nil when unchecked
+	UpdatedBy   string         `json:"updated_by"`            // Author of the latest change
+	Version     int            `json:"version"`               // Incremented on every write, equals the latest revision
+	CreatedAt   int64          `json:"created_at"`            // Unix timestamp
+	UpdatedAt   int64          `json:"updated_at"`            // Unix timestamp
+}
+
+// Revision is an immutable snapshot of a ruleset recorded on every create and update.
+type Revision struct {
+	RulesetName string         `json:"ruleset_name"`
+	Revision    int            `json:"revision"` // Sequence number per ruleset, starting at 1
+	Author      string         `json:"author"`
+	Description string         `json:"description"`
+	Salience    int            `json:"salience"`
+	GRL         string         `json:"grl"`
+	Tags        []string       `json:"tags"`
+	FactSchema  map[string]any `json:"fact_schema,omitempty"` // nil when unchecked
+	CreatedAt   int64          `json:"created_at"`            // Unix timestamp
+}
+
+// Pipeline chains rulesets evaluated one after the other on the same facts.
+type Pipeline struct {
+	ID          string  `json:"id"`
+	Name        string  `json:"name"`
+	Description string  `json:"description"`
+	Stages      []Stage `json:"stages"`     // Evaluated in order
+	CreatedAt   int64   `json:"created_at"` // Unix timestamp
+	UpdatedAt   int64   `json:"updated_at"` // Unix timestamp
+}
+
+// Stage is a step of a pipeline.
+type Stage struct {
+	Ruleset  string         `json:"ruleset"`             // Name of the ruleset evaluated by the stage
+	StopWhen *StopCondition `json:"stop_when,omitempty"` // Ends the pipeline after this stage when it holds
+}
+
+// StopCondition holds when the fact Key equals Equals, or when Key is set if Equals is omitted.
+type StopCondition struct {
+	Key    string `json:"key"`
+	Equals any    `json:"equals,omitempty"`
 }
 
 // IRulesetStorage defines the interface for database operations on rules.
@@ -31,10 +80,29 @@ type IRulesetStorage interface {
 	GetAll(ctx context.Context) ([]Ruleset, error)
 	// GetByName retrieves a ruleset by its name.
 	GetByName(ctx context.Context, name string) (*Ruleset, error)
-	// Create adds a new ruleset to the database.
+	// Create adds a new ruleset to the database and records its first revision.
 	Create(ctx context.Context, rule Ruleset) (string, error)
-	// Update modifies an existing ruleset identified by name.
+	// Update modifies an existing ruleset identified by name and records a new revision.
+	// It fails with ErrConflict unless rule.Version is the stored version, which is then incremented.
 	Update(ctx context.Context, name string, rule Ruleset) error
-	// Delete removes a ruleset identified by name.
+	// Delete removes a ruleset identified by name, together with its revisions.
 	Delete(ctx context.Context, name string) error
+	// GetRevisions retrieves the revisions of a ruleset, oldest first.
+	GetRevisions(ctx context.Context, name string) ([]Revision, error)
+	// GetRevision retrieves a single revision of a ruleset.
+	GetRevision(ctx context.Context, name string, revision int) (*Revision, error)
+}
+
+// IPipelineStorage defines the interface for database operations on pipelines.
+type IPipelineStorage interface {
+	// GetAllPipelines retrieves all pipelines.
+	GetAllPipelines(ctx context.Context) ([]Pipeline, error)
+	// GetPipeline retrieves a pipeline by its name.
+	GetPipeline(ctx context.Context, name string) (*Pipeline, error)
+	// CreatePipeline adds a new pipeline to the database.
+	CreatePipeline(ctx context.Context, pipeline Pipeline) (string, error)
+	// UpdatePipeline modifies an existing pipeline identified by name.
+	UpdatePipeline(ctx context.Context, name string, pipeline Pipeline) error
+	// DeletePipeline removes a pipeline identified by name.
+	DeletePipeline(ctx context.Context, name string) error
 }
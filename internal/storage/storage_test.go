@@ -0,0 +1,284 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/hungpdn/mcp2grule/internal/config"
+)
+
+// store is implemented by every storage backend
+type store interface {
+	IRulesetStorage
+	IPipelineStorage
+}
+
+func TestMemoryStorage(t *testing.T) {
+
+	testStorage(t, func(t *testing.T) store {
+		return NewMemory()
+	})
+}
+
+func TestSQLiteStorage(t *testing.T) {
+
+	testStorage(t, func(t *testing.T) store {
+		db, err := OpenSQLite(config.SQLite{Path: filepath.Join(t.TempDir(), "mcp2grule.db")})
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { _ = db.Close() })
+
+		m, err := NewMigrator(db, DialectSQLite)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := m.Up(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+
+		return NewSQLite(db)
+	})
+}
+
+// testStorage runs the behaviour shared by all storage backends, newStore returns an empty store
+func testStorage(t *testing.T, newStore func(t *testing.T) store) {
+
+	ctx := context.Background()
+	schema := map[string]any{
+		"type":       "object",
+		"properties": map[string]any{"amount": map[string]any{"type": "number"}},
+		"required":   []any{"amount"},
+	}
+
+	t.Run("rulesets", func(t *testing.T) {
+		s := newStore(t)
+		loan := Ruleset{Name: "loan", Description: "Loan approval", Salience: 5, GRL: "v1", Tags: []string{"credit", "retail"}, FactSchema: schema, UpdatedBy: "alice"}
+		if _, err := s.Create(ctx, loan); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.Create(ctx, Ruleset{Name: "fraud", GRL: "v1"}); err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := s.GetByName(ctx, "loan")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.ID == "" || got.Version != 1 || got.CreatedAt == 0 || got.UpdatedAt != got.CreatedAt {
+			t.Fatalf("ruleset %+v, want an ID, version 1 and the creation time", got)
+		}
+		if got.Description != loan.Description || got.Salience != loan.Salience || got.GRL != loan.GRL || got.UpdatedBy != loan.UpdatedBy {
+			t.Fatalf("ruleset %+v, want %+v", got, loan)
+		}
+		if !slices.Equal(got.Tags, loan.Tags) || !reflect.DeepEqual(got.FactSchema, schema) {
+			t.Fatalf("tags %v and fact schema %v, want %v and %v", got.Tags, got.FactSchema, loan.Tags, schema)
+		}
+
+		fraud, err := s.GetByName(ctx, "fraud")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fraud.Tags == nil || len(fraud.Tags) != 0 || fraud.FactSchema != nil {
+			t.Fatalf("tags %#v and fact schema %v, want no tags and no schema", fraud.Tags, fraud.FactSchema)
+		}
+
+		all, err := s.GetAll(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		names := make([]string, 0, len(all))
+		for _, r := range all {
+			names = append(names, r.Name)
+		}
+		slices.Sort(names)
+		if !slices.Equal(names, []string{"fraud", "loan"}) {
+			t.Fatalf("rulesets %v, want fraud and loan", names)
+		}
+
+		errs := []struct {
+			name string
+			call func() error
+			want error
+		}{
+			{"create a duplicate", func() error { _, err := s.Create(ctx, Ruleset{Name: "loan", GRL: "v2"}); return err }, ErrAlreadyExists},
+			{"get a missing ruleset", func() error { _, err := s.GetByName(ctx, "nope"); return err }, ErrNotFound},
+			{"update a missing ruleset", func() error { return s.Update(ctx, "nope", Ruleset{Name: "nope", Version: 1}) }, ErrNotFound},
+			{"delete a missing ruleset", func() error { return s.Delete(ctx, "nope") }, ErrNotFound},
+		}
+		for _, tt := range errs {
+			t.Run(tt.name, func(t *testing.T) {
+				if err := tt.call(); !errors.Is(err, tt.want) {
+					t.Fatalf("%s = %v, want %v", tt.name, err, tt.want)
+				}
+			})
+		}
+	})
+
+	t.Run("revisions", func(t *testing.T) {
+		s := newStore(t)
+		if _, err := s.Create(ctx, Ruleset{Name: "loan", GRL: "v1", Tags: []string{"credit"}, UpdatedBy: "alice"}); err != nil {
+			t.Fatal(err)
+		}
+
+		updates := []struct {
+			name    string
+			version int   // expected version sent with the update
+			want    error // nil when the update is stored
+			stored  int   // version stored after the update
+		}{
+			{"current version", 1, nil, 2},
+			{"stale version", 1, ErrConflict, 2},
+			{"version ahead", 5, ErrConflict, 2},
+			{"next current version", 2, nil, 3},
+		}
+		for _, tt := range updates {
+			t.Run(tt.name, func(t *testing.T) {
+				current, err := s.GetByName(ctx, "loan")
+				if err != nil {
+					t.Fatal(err)
+				}
+				next := *current
+				next.GRL = tt.name
+				next.Tags = []string{"credit", tt.name}
+				next.FactSchema = schema
+				next.UpdatedBy = "bob"
+				next.Version = tt.version
+
+				if err := s.Update(ctx, "loan", next); !errors.Is(err, tt.want) {
+					t.Fatalf("Update() = %v, want %v", err, tt.want)
+				}
+				rule, err := s.GetByName(ctx, "loan")
+				if err != nil {
+					t.Fatal(err)
+				}
+				if rule.Version != tt.stored {
+					t.Fatalf("stored version %d, want %d", rule.Version, tt.stored)
+				}
+				if tt.want == nil && (rule.GRL != tt.name || !slices.Equal(rule.Tags, next.Tags)) {
+					t.Fatalf("ruleset %+v, want the update stored", rule)
+				}
+			})
+		}
+
+		revisions, err := s.GetRevisions(ctx, "loan")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(revisions) != 3 {
+			t.Fatalf("%d revisions, want 3: a refused update must not record one", len(revisions))
+		}
+		first, last := revisions[0], revisions[2]
+		if first.Revision != 1 || first.GRL != "v1" || first.Author != "alice" || !slices.Equal(first.Tags, []string{"credit"}) || first.FactSchema != nil {
+			t.Fatalf("first revision %+v, want the created ruleset", first)
+		}
+		if last.Revision != 3 || last.GRL != "next current version" || last.Author != "bob" ||
+			!slices.Equal(last.Tags, []string{"credit", "next current version"}) || !reflect.DeepEqual(last.FactSchema, schema) {
+			t.Fatalf("last revision %+v, want the last update", last)
+		}
+
+		got, err := s.GetRevision(ctx, "loan", 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.GRL != "current version" || !reflect.DeepEqual(got.FactSchema, schema) {
+			t.Fatalf("revision 2 %+v, want the first update", got)
+		}
+		if _, err := s.GetRevision(ctx, "loan", 4); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("GetRevision(4) = %v, want %v", err, ErrNotFound)
+		}
+
+		if err := s.Delete(ctx, "loan"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.GetRevisions(ctx, "loan"); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("GetRevisions() after delete = %v, want %v", err, ErrNotFound)
+		}
+
+		// a ruleset created again under the same name starts a new history
+		if _, err := s.Create(ctx, Ruleset{Name: "loan", GRL: "again"}); err != nil {
+			t.Fatal(err)
+		}
+		revisions, err = s.GetRevisions(ctx, "loan")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(revisions) != 1 || revisions[0].Revision != 1 || revisions[0].GRL != "again" {
+			t.Fatalf("revisions %+v, want a single first revision", revisions)
+		}
+	})
+
+	t.Run("pipelines", func(t *testing.T) {
+		s := newStore(t)
+		stages := []Stage{
+			{Ruleset: "score"},
+			{Ruleset: "approve", StopWhen: &StopCondition{Key: "approved", Equals: true}},
+			{Ruleset: "notify", StopWhen: &StopCondition{Key: "notified"}},
+		}
+		if _, err := s.CreatePipeline(ctx, Pipeline{Name: "loan", Description: "Loan flow", Stages: stages}); err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := s.GetPipeline(ctx, "loan")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.ID == "" || got.Description != "Loan flow" || got.CreatedAt == 0 || !reflect.DeepEqual(got.Stages, stages) {
+			t.Fatalf("pipeline %+v, want the created one", got)
+		}
+
+		updated := *got
+		updated.Description = "Short loan flow"
+		updated.Stages = stages[:1]
+		if err := s.UpdatePipeline(ctx, "loan", updated); err != nil {
+			t.Fatal(err)
+		}
+		got, err = s.GetPipeline(ctx, "loan")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.ID != updated.ID || got.Description != "Short loan flow" || !reflect.DeepEqual(got.Stages, stages[:1]) {
+			t.Fatalf("pipeline %+v, want the updated one", got)
+		}
+
+		all, err := s.GetAllPipelines(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(all) != 1 || all[0].Name != "loan" {
+			t.Fatalf("pipelines %+v, want loan", all)
+		}
+
+		if err := s.DeletePipeline(ctx, "loan"); err != nil {
+			t.Fatal(err)
+		}
+
+		errs := []struct {
+			name string
+			call func() error
+			want error
+		}{
+			{"get a deleted pipeline", func() error { _, err := s.GetPipeline(ctx, "loan"); return err }, ErrNotFound},
+			{"update a missing pipeline", func() error { return s.UpdatePipeline(ctx, "nope", Pipeline{Name: "nope", Stages: stages}) }, ErrNotFound},
+			{"delete a missing pipeline", func() error { return s.DeletePipeline(ctx, "nope") }, ErrNotFound},
+			{"create a duplicate", func() error {
+				if _, err := s.CreatePipeline(ctx, Pipeline{Name: "fraud", Stages: stages}); err != nil {
+					return err
+				}
+				_, err := s.CreatePipeline(ctx, Pipeline{Name: "fraud", Stages: stages})
+				return err
+			}, ErrAlreadyExists},
+		}
+		for _, tt := range errs {
+			t.Run(tt.name, func(t *testing.T) {
+				if err := tt.call(); !errors.Is(err, tt.want) {
+					t.Fatalf("%s = %v, want %v", tt.name, err, tt.want)
+				}
+			})
+		}
+	})
+}